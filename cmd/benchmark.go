@@ -1,42 +1,187 @@
 // The benchmark command is a utility that benchmarks different image build/push methods, calculates the average
 // run time for each, and outputs the result to a csv file.
+// Running it as "benchmark cleanup" instead removes any cluster or container left over by a previous benchmark.
 package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 
 	"benchmark/pkg/benchmark"
 	"benchmark/pkg/command"
 	"benchmark/pkg/csv"
 	"benchmark/pkg/download"
+	"benchmark/pkg/safety"
 )
 
 func main() {
 	runs := flag.Int("runs", 100, "number of runs per benchmark")
-	profile := flag.String("profile", "benchmark", "profile to use for minikube commands")
+	profile := flag.String("profile", "benchmark", "name of the cluster created by every provider, used as the minikube profile and the kind and k3d cluster name")
 	images := flag.String("images", "", "a comma separated list of images to benchmark")
 	benchFlows := flag.String("iters", "iterative,non-iterative", "a comma separated list of flows to benchmark, options [iterative,non-iterative]")
 	benchMethods := flag.String("bench-methods", "", "a comma separated list of benchmark method names")
-	memory := flag.String("memory", "", "Amount of RAM to allocate to Kubernetes (format: <number>[<unit>], where unit = b, k, m or g). Use \"max\" to use the maximum amount of memory")
+	builders := flag.String("builders", "", "a comma separated list of Docker builders to cross the docker based methods with, options [legacy,buildkit,buildx]")
+	buildOpts := flag.String("image-build-opts", "", "a comma separated list of options passed to the image build methods via --build-opt")
+	buildEnvs := flag.String("image-build-envs", "", "a comma separated list of environment variables passed to the image build methods via --build-env")
+	buildPush := flag.Bool("image-build-push", false, "push the image built by the image build methods to the registry addon")
+	buildkitHost := flag.String("buildkit-host", "", "address of the buildkitd used by the containerd image build method (e.g. unix:///run/buildkit/buildkitd.sock)")
+	drivers := flag.String("drivers", "", "a comma separated list of minikube drivers to cross the minikube methods with, options [docker,podman,kvm2,qemu,none]")
+	kubernetesVersions := flag.String("kubernetes-versions", "", "a comma separated list of Kubernetes versions to cross the minikube, kind and k3d methods with (e.g. v1.28.0,v1.29.0)")
+	nodes := flag.Int("nodes", 1, "number of nodes to start minikube with, if greater than 1 only the methods supporting multiple nodes are run")
+	reuseCluster := flag.Bool("reuse-cluster", false, "reuse the existing cluster named after --profile instead of starting and deleting one for every method, only clearing the cache between runs")
+	iUnderstand := flag.Bool("i-understand", false, "run even though minikube profiles or kind/k3d clusters not created by the benchmark exist and may be deleted")
+	isolatedDocker := flag.Bool("isolated-docker", false, "run Docker commands against a dedicated Docker daemon started by the benchmark, which is removed at the end")
+	minikubeStartArgs := flag.String("minikube-start-args", "", "a space separated list of extra args passed to minikube start")
+	kindConfig := flag.String("kind-config", "", "path to a kind cluster config the kind clusters are created with")
+	k3dArgs := flag.String("k3d-args", "", "a space separated list of extra args passed to k3d cluster create")
+	memory := flag.String("memory", "", "a comma separated list of amounts of RAM to allocate to the minikube node (format: <number>[<unit>], where unit = b, k, m or g), use \"max\" to use the maximum amount of memory")
+	cpus := flag.String("cpus", "", "a comma separated list of numbers of CPUs to allocate to the minikube node, use \"max\" to use the maximum number of CPUs")
+	diskSizes := flag.String("disk-size", "", "a comma separated list of disk sizes to allocate to the minikube node (format: <number>[<unit>], where unit = b, k, m or g)")
 
-	flag.Parse()
+	// the cleanup command accepts the same flags, only --profile is used, a reused cluster is deleted as well
+	// as there's no way to tell whether the leftover cluster was created by the benchmark
+	cleanup := len(os.Args) > 1 && os.Args[1] == "cleanup"
+	args := os.Args[1:]
+	if cleanup {
+		args = os.Args[2:]
+	}
+	flag.CommandLine.Parse(args)
+
+	if cleanup {
+		if err := runCleanup(command.RunOptions{Profile: *profile}); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
 	if *runs <= 0 {
 		log.Fatalf("--runs must be 1 or greater")
 	}
 
+	if *nodes <= 0 {
+		log.Fatalf("--nodes must be 1 or greater")
+	}
+
+	if *reuseCluster && *isolatedDocker {
+		log.Fatalf("--reuse-cluster can't be used with --isolated-docker, as the existing clusters aren't visible to the isolated Docker daemon")
+	}
+
+	if *reuseCluster {
+		// the reused cluster isn't started by the benchmark, so the flags shaping the started clusters would only mislabel its results
+		for _, f := range []struct {
+			name string
+			set  bool
+		}{
+			{"--drivers", *drivers != ""},
+			{"--memory", *memory != ""},
+			{"--cpus", *cpus != ""},
+			{"--disk-size", *diskSizes != ""},
+			{"--kubernetes-versions", *kubernetesVersions != ""},
+			{"--nodes", *nodes > 1},
+			{"--minikube-start-args", *minikubeStartArgs != ""},
+			{"--kind-config", *kindConfig != ""},
+			{"--k3d-args", *k3dArgs != ""},
+		} {
+			if f.set {
+				log.Fatalf("--reuse-cluster can't be used with %s, as the existing cluster is benchmarked as it was started", f.name)
+			}
+		}
+		if *buildPush {
+			log.Fatalf("--reuse-cluster can't be used with --image-build-push, as the registry addon of the existing cluster can't be emptied between runs")
+		}
+	}
+
+	for _, builder := range splitList(*builders) {
+		if !validBuilder(command.Builder(builder)) {
+			log.Fatalf("--builders contains unknown builder %q", builder)
+		}
+	}
+
+	for _, driver := range splitList(*drivers) {
+		if err := command.CheckDriver(driver); err != nil {
+			log.Fatalf("--drivers contains a driver that can't be used: %v", err)
+		}
+	}
+
 	if err := download.Files(); err != nil {
 		log.Fatal(err)
 	}
 
-	defer command.Delete()
+	snapshot, err := safety.Take(*profile)
+	if err != nil {
+		log.Fatalf("failed to snapshot host state: %v", err)
+	}
+	if err := os.MkdirAll("out", 0755); err != nil {
+		log.Fatalf("failed to create out dir: %v", err)
+	}
+	if err := snapshot.Save("out/host-state.json"); err != nil {
+		log.Fatal(err)
+	}
+	if unrelated := snapshot.Unrelated(); len(unrelated) != 0 && !*iUnderstand {
+		log.Fatalf("the following state wasn't created by the benchmark and may be deleted by it:\n  %s\nthe full host state was saved to out/host-state.json, rerun with --i-understand to proceed", strings.Join(unrelated, "\n  "))
+	}
+	stopSandbox := func() {
+		if err := command.StopDockerSandbox(); err != nil {
+			log.Printf("failed to stop isolated Docker daemon: %v", err)
+		}
+	}
+	if *isolatedDocker {
+		if err := command.StartDockerSandbox(); err != nil {
+			stopSandbox()
+			log.Fatal(err)
+		}
+	}
+
+	// remove the clusters and containers created by the benchmark once it exits, or is interrupted,
+	// the isolated Docker daemon is stopped after them as the clusters run in it,
+	// then the kubeconfig context the clusters switched to is switched back
+	var teardownOnce sync.Once
+	teardown := func() {
+		teardownOnce.Do(func() {
+			if err := command.Cleanup(command.RunOptions{Profile: *profile, ReuseCluster: *reuseCluster}); err != nil {
+				log.Printf("failed to clean up: %v", err)
+			}
+			stopSandbox()
+			if err := snapshot.Restore(); err != nil {
+				log.Printf("failed to restore host state: %v", err)
+			}
+		})
+	}
+	defer teardown()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupt
+		teardown()
+		os.Exit(1)
+	}()
 
-	extraMinikubeStartArgs := []string{}
-	if *memory != "" {
-		extraMinikubeStartArgs = append(extraMinikubeStartArgs, "--memory="+*memory)
+	startArgs := map[string][]string{
+		command.ProviderMinikube: strings.Fields(*minikubeStartArgs),
+		command.ProviderK3d:      strings.Fields(*k3dArgs),
+	}
+	if *kindConfig != "" {
+		startArgs[command.ProviderKind] = []string{"--config", *kindConfig}
 	}
-	results, err := benchmark.Run(*runs, benchmark.NewBenchmarkRunConfig(*profile, *images, *benchFlows, *benchMethods, extraMinikubeStartArgs))
+	config := benchmark.NewBenchmarkRunConfig(*profile, *images, *benchFlows, *benchMethods, *builders, startArgs)
+	config.ImageBuild = command.ImageBuildOptions{
+		BuildOpts:    splitList(*buildOpts),
+		BuildEnvs:    splitList(*buildEnvs),
+		Push:         *buildPush,
+		BuildkitHost: *buildkitHost,
+	}
+	config.Nodes = *nodes
+	config.ReuseCluster = *reuseCluster
+	config.Drivers = splitList(*drivers)
+	config.KubernetesVersions = splitList(*kubernetesVersions)
+	config.Resources = benchmark.ResourceSweep(splitList(*memory), splitList(*cpus), splitList(*diskSizes))
+	results, err := benchmark.Run(*runs, config)
 	if err != nil {
 		log.Printf("failed running benchmarks: %v", err)
 		return
@@ -46,3 +191,33 @@ func main() {
 		return
 	}
 }
+
+// runCleanup removes the clusters of every provider and the containers left over by a previous benchmark,
+// including the isolated Docker daemon.
+func runCleanup(opts command.RunOptions) error {
+	if err := command.Cleanup(opts); err != nil {
+		return fmt.Errorf("failed to clean up: %v", err)
+	}
+	if err := command.DeleteDockerSandbox(); err != nil {
+		return fmt.Errorf("failed to clean up: %v", err)
+	}
+	return nil
+}
+
+// validBuilder returns whether the provided builder is one that can be selected.
+func validBuilder(builder command.Builder) bool {
+	for _, b := range command.Builders {
+		if b == builder {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList splits the provided comma separated list, an empty list results in no elements.
+func splitList(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
@@ -8,35 +8,173 @@ import (
 	"math"
 	"os/exec"
 	"strings"
+	"time"
 
 	"benchmark/pkg/command"
 )
 
-type runResultsMatrix map[string]map[string][]float64
+type runResultsMatrix map[string]map[string][]command.Timing
+
+// cellTiming contains the time spent on a single image method combination, apart from the runs themselves, in seconds.
+type cellTiming struct {
+	// Wall is the wall-clock time spent on the combination, including clearing the cache and preparing the runs.
+	Wall float64
+	// CacheClears contains the time taken by every cache clear.
+	CacheClears []float64
+}
+
+type cellTimingMatrix map[string]map[string]cellTiming
 
 type aggregatedRunResult struct {
 	Avg float64
 	Std float64
+	// Throughput is the image size divided by the average transfer time, in MB/s, it's only reported for the non-iterative flow.
+	Throughput float64
+	// SaveAvg is the average time spent saving the image to an archive.
+	SaveAvg float64
+	// CacheClearAvg is the average time taken by a cache clear.
+	CacheClearAvg float64
+	// Wall is the wall-clock time spent on the combination.
+	Wall float64
+	// Measured is the part of the wall-clock time spent in the runs that make up the results.
+	Measured float64
+	// Overhead is the rest of the wall-clock time, spent clearing the cache, preparing and on discarded runs.
+	Overhead float64
 }
 
 // AggregatedResultsMatrix is a map containing the run results for every image method combination.
 type AggregatedResultsMatrix map[string]map[string]aggregatedRunResult
 
+// Results contains the aggregated run results along with the metadata of every benchmarked image.
+type Results struct {
+	Matrix AggregatedResultsMatrix
+	Images map[string]command.ImageInfo
+	// Methods contains the names of every benchmarked method variant, in the order they were run.
+	Methods []string
+	// StartCommands contains the command the cluster of every benchmarked method variant was started with.
+	StartCommands map[string]string
+	// ClusterTimes contains the time spent starting and deleting the cluster of every benchmarked method variant.
+	ClusterTimes map[string]ClusterTiming
+}
+
+// ClusterTiming contains the time spent on the cluster of a method in seconds, steps that failed are NaN.
+type ClusterTiming struct {
+	// Create is the time taken by the provider to create and start the cluster.
+	Create float64
+	// Ready is the time taken by the API server and every node to become ready once the cluster was started.
+	Ready float64
+	// Setup is the time taken by the method to prepare the ready cluster, such as enabling addons or running registry containers.
+	Setup float64
+	// Delete is the time taken to delete the cluster along with anything else started for the method.
+	Delete float64
+}
+
 type BenchmarkRunConfig struct {
-	BenchMethods      map[string]struct{}
-	Iters             map[string]struct{}
-	Images            map[string]struct{}
-	MinikubeStartArgs []string
-	Profile           string
+	BenchMethods map[string]struct{}
+	Iters        map[string]struct{}
+	Images       map[string]struct{}
+	// StartArgs contains the args passed when starting the cluster of every provider, keyed by provider name.
+	StartArgs map[string][]string
+	Profile   string
+	// Builders contains the builders every docker based method is crossed with.
+	// If empty, each method uses its own default builder.
+	Builders []command.Builder
+	// ImageBuild contains the options passed to every image build method.
+	ImageBuild command.ImageBuildOptions
+	// Drivers contains the drivers every minikube method is crossed with.
+	// If empty, each method uses minikube's default driver.
+	Drivers []string
+	// KubernetesVersions contains the Kubernetes versions every method whose provider supports selecting one is crossed with.
+	// If empty, each method uses its provider's default version.
+	KubernetesVersions []string
+	// Resources contains the node resources every minikube method is crossed with.
+	// If empty, each method uses minikube's default resources.
+	Resources []Resources
+	// Nodes is the number of nodes the minikube cluster is started with.
+	// If greater than 1, only the methods supporting multiple nodes are run.
+	Nodes int
+	// ReuseCluster reuses the existing cluster named after the profile instead of starting and deleting one for every method.
+	ReuseCluster bool
+}
+
+// selected returns whether the provided method is run.
+func (c *BenchmarkRunConfig) selected(m method) bool {
+	if _, ok := c.BenchMethods[m.Name]; !ok {
+		return false
+	}
+	if m.registryAddon && c.ReuseCluster {
+		return false
+	}
+	return c.Nodes < 2 || m.multiNode
+}
+
+// Resources contains the resources allocated to a cluster node, empty values are left to the provider's default.
+type Resources struct {
+	Memory   string
+	CPUs     string
+	DiskSize string
+}
+
+// ResourceSweep returns every combination of the provided memory, cpus and disk sizes.
+// Dimensions without values are left to the provider's default, if none have values there's nothing to sweep.
+func ResourceSweep(memory, cpus, diskSizes []string) []Resources {
+	if len(memory) == 0 && len(cpus) == 0 && len(diskSizes) == 0 {
+		return nil
+	}
+	orDefault := func(values []string) []string {
+		if len(values) == 0 {
+			return []string{""}
+		}
+		return values
+	}
+	res := []Resources{}
+	for _, m := range orDefault(memory) {
+		for _, c := range orDefault(cpus) {
+			for _, d := range orDefault(diskSizes) {
+				res = append(res, Resources{Memory: m, CPUs: c, DiskSize: d})
+			}
+		}
+	}
+	return res
+}
+
+// startArgs returns the minikube start args allocating the resources.
+func (r Resources) startArgs() []string {
+	args := []string{}
+	if r.Memory != "" {
+		args = append(args, "--memory="+r.Memory)
+	}
+	if r.CPUs != "" {
+		args = append(args, "--cpus="+r.CPUs)
+	}
+	if r.DiskSize != "" {
+		args = append(args, "--disk-size="+r.DiskSize)
+	}
+	return args
+}
+
+// labels returns the labels describing the resources.
+func (r Resources) labels() []string {
+	labels := []string{}
+	if r.Memory != "" {
+		labels = append(labels, "memory "+r.Memory)
+	}
+	if r.CPUs != "" {
+		labels = append(labels, "cpus "+r.CPUs)
+	}
+	if r.DiskSize != "" {
+		labels = append(labels, "disk "+r.DiskSize)
+	}
+	return labels
 }
 
-func NewBenchmarkRunConfig(profile, imageList, iterList, benchMethodList string, minikubeStartArgs []string) *BenchmarkRunConfig {
+func NewBenchmarkRunConfig(profile, imageList, iterList, benchMethodList, builderList string, startArgs map[string][]string) *BenchmarkRunConfig {
 	res := BenchmarkRunConfig{
-		BenchMethods:      make(map[string]struct{}),
-		Iters:             make(map[string]struct{}),
-		Images:            make(map[string]struct{}),
-		MinikubeStartArgs: minikubeStartArgs,
-		Profile:           profile,
+		BenchMethods: make(map[string]struct{}),
+		Iters:        make(map[string]struct{}),
+		Images:       make(map[string]struct{}),
+		StartArgs:    startArgs,
+		Profile:      profile,
 	}
 	split := Images
 	if imageList != "" {
@@ -68,15 +206,54 @@ func NewBenchmarkRunConfig(profile, imageList, iterList, benchMethodList string,
 		}
 	}
 
+	if builderList != "" {
+		for _, builder := range strings.Split(builderList, ",") {
+			res.Builders = append(res.Builders, command.Builder(builder))
+		}
+	}
+
 	return &res
 
 }
 
 type method struct {
-	startMinikube func(profile string, args ...string) error
-	bench         func(image string, profile string) (float64, error)
-	cacheClear    func(profile string) error
-	Name          string
+	startMinikube func(opts command.RunOptions, args ...string) (command.ClusterStart, error)
+	bench         func(image string, opts command.RunOptions) (command.Timing, error)
+	cacheClear    func(opts command.RunOptions) error
+	// teardown deletes the cluster along with anything else started by startMinikube.
+	teardown func(opts command.RunOptions) error
+	// provider is the name of the provider of the cluster started by startMinikube.
+	provider string
+	Name     string
+	// builder is the builder used by methods that build with Docker, it is empty for every other method.
+	builder command.Builder
+	// labels describe the dimensions this variant of the method was crossed with.
+	labels []string
+	// multiNode is set on the methods that make the image available on every node of a multi-node cluster.
+	multiNode bool
+	// registryAddon is set on the methods pushing to the registry addon, which is emptied between runs.
+	registryAddon bool
+	// startArgs are the args this variant of the method passes when starting the cluster, on top of the provider's start args.
+	startArgs []string
+}
+
+// fullName returns the name of the method variant, which is used to identify its results.
+func (m method) fullName() string {
+	if len(m.labels) == 0 {
+		return m.Name
+	}
+	return fmt.Sprintf("%s (%s)", m.Name, strings.Join(m.labels, ", "))
+}
+
+// runOptions returns the options every run of the method is carried out with.
+func (m method) runOptions(config *BenchmarkRunConfig) command.RunOptions {
+	return command.RunOptions{
+		Profile:      config.Profile,
+		Builder:      m.builder,
+		ImageBuild:   config.ImageBuild,
+		Nodes:        config.Nodes,
+		ReuseCluster: config.ReuseCluster,
+	}
 }
 
 // Images is the list of all the images to use for benchmarking
@@ -88,122 +265,443 @@ var Iter = []string{" iterative", " non-iterative"}
 // BenchMethods contains an array of benchmarking funcs
 var BenchMethods = []method{
 	{
-		command.StartMinikubeImageLoadDocker,
-		command.RunImageLoad,
-		command.ClearDockerAndMinikubeDockerCache,
-		"image load docker",
+		startMinikube: command.StartMinikubeImageLoadDocker,
+		bench:         command.RunImageLoad,
+		cacheClear:    command.ClearDockerAndMinikubeDockerCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "image load docker",
+		multiNode:     true,
+		builder:       command.BuilderDefault,
+	},
+	{
+		startMinikube: command.StartMinikubeImageLoadDocker,
+		bench:         command.RunImageLoadArchive,
+		cacheClear:    command.ClearDockerAndMinikubeDockerCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "image load archive docker",
+		builder:       command.BuilderDefault,
+	},
+	{
+		startMinikube: command.StartMinikubeImageLoadRemoteDocker,
+		bench:         command.RunImageLoadRemote,
+		cacheClear:    command.ClearLocalRegistryAndDockerAndMinikubeDockerCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "image load remote docker",
+		builder:       command.BuilderDefault,
+	},
+	{
+		startMinikube: command.StartMinikubeImageBuildDocker,
+		bench:         command.RunImageBuild,
+		cacheClear:    command.ClearImageBuildDockerCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "image build docker",
+		multiNode:     true,
+	},
+	{
+		startMinikube: command.StartMinikubeDockerEnv,
+		bench:         command.RunDockerEnv,
+		cacheClear:    command.ClearDockerAndMinikubeDockerCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "docker-env docker",
+		multiNode:     true,
+		builder:       command.BuilderDefault,
+	},
+	{
+		startMinikube: command.StartMinikubeRegistryDocker,
+		bench:         command.RunRegistry,
+		cacheClear:    command.ClearRegistryAddonAndDockerAndMinikubeDockerCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "registry docker",
+		registryAddon: true,
+		multiNode:     true,
+		builder:       command.BuilderDefault,
+	},
+	{
+		startMinikube: command.StartMinikubeImageLoadContainerd,
+		bench:         command.RunImageLoad,
+		cacheClear:    command.ClearDockerAndMinikubeNodeCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "image load containerd",
+		multiNode:     true,
+		builder:       command.BuilderDefault,
+	},
+	{
+		startMinikube: command.StartMinikubeImageLoadContainerd,
+		bench:         command.RunImageLoadArchive,
+		cacheClear:    command.ClearDockerAndMinikubeNodeCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "image load archive containerd",
+		builder:       command.BuilderDefault,
+	},
+	{
+		startMinikube: command.StartMinikubeImageLoadRemoteContainerd,
+		bench:         command.RunImageLoadRemote,
+		cacheClear:    command.ClearLocalRegistryAndDockerAndMinikubeNodeCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "image load remote containerd",
+		builder:       command.BuilderDefault,
+	},
+	{
+		startMinikube: command.StartMinikubeImageBuildContainerd,
+		bench:         command.RunImageBuildContainerd,
+		cacheClear:    command.ClearImageBuildCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "image build containerd",
+		multiNode:     true,
+	},
+	{
+		startMinikube: command.StartMinikubeDockerEnvContainerd,
+		bench:         command.RunDockerEnv,
+		cacheClear:    command.ClearDockerEnvAndMinikubeNodeCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "docker-env containerd",
+		builder:       command.BuilderLegacy,
+	},
+	{
+		startMinikube: command.StartMinikubeRegistryContainerd,
+		bench:         command.RunRegistry,
+		cacheClear:    command.ClearRegistryAddonAndDockerAndMinikubeNodeCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "registry containerd",
+		registryAddon: true,
+		multiNode:     true,
+		builder:       command.BuilderDefault,
+	},
+	{
+		startMinikube: command.StartMinikubeImageLoadCrio,
+		bench:         command.RunImageLoad,
+		cacheClear:    command.ClearDockerAndMinikubeNodeCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "image load crio",
+		multiNode:     true,
+		builder:       command.BuilderDefault,
+	},
+	{
+		startMinikube: command.StartMinikubeImageLoadCrio,
+		bench:         command.RunImageLoadArchive,
+		cacheClear:    command.ClearDockerAndMinikubeNodeCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "image load archive crio",
+		builder:       command.BuilderDefault,
+	},
+	{
+		startMinikube: command.StartMinikubeImageLoadRemoteCrio,
+		bench:         command.RunImageLoadRemote,
+		cacheClear:    command.ClearLocalRegistryAndDockerAndMinikubeNodeCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "image load remote crio",
+		builder:       command.BuilderDefault,
+	},
+	{
+		startMinikube: command.StartMinikubeImageBuildCrio,
+		bench:         command.RunImageBuild,
+		cacheClear:    command.ClearImageBuildCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "image build crio",
+		multiNode:     true,
+	},
+	{
+		startMinikube: command.StartMinikubeRegistryCrio,
+		bench:         command.RunRegistry,
+		cacheClear:    command.ClearRegistryAddonAndDockerAndMinikubeNodeCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "registry crio",
+		registryAddon: true,
+		multiNode:     true,
+		builder:       command.BuilderDefault,
+	},
+	{
+		startMinikube: command.StartMinikubePodmanEnv,
+		bench:         command.RunPodmanEnv,
+		cacheClear:    command.ClearMinikubePodmanCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "podman-env crio",
+	},
+	{
+		startMinikube: command.StartKind,
+		bench:         command.RunKind,
+		cacheClear:    command.ClearKindCache,
+		teardown:      command.DeleteKind,
+		provider:      command.ProviderKind,
+		Name:          "kind",
+		builder:       command.BuilderDefault,
+	},
+	{
+		startMinikube: command.StartK3d,
+		bench:         command.RunK3d,
+		cacheClear:    command.ClearK3dCache,
+		teardown:      command.DeleteK3d,
+		provider:      command.ProviderK3d,
+		Name:          "k3d",
+		builder:       command.BuilderDefault,
+	},
+	{
+		startMinikube: command.StartMicrok8s,
+		bench:         command.RunMicrok8s,
+		cacheClear:    command.ClearMicrok8sCache,
+		teardown:      command.DeleteMicrok8s,
+		provider:      command.ProviderMicrok8s,
+		Name:          "microk8s local image",
+		builder:       command.BuilderDefault,
+	},
+	{
+		startMinikube: command.StartMinikubeImageLoadContainerd,
+		bench:         command.RunNerdctlImageLoad,
+		cacheClear:    command.ClearNerdctlAndMinikubeNodeCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "nerdctl image load containerd",
+	},
+	{
+		startMinikube: command.StartMinikubeRegistryNerdctl,
+		bench:         command.RunNerdctlRegistry,
+		cacheClear:    command.ClearRegistryAddonAndNerdctlAndMinikubeNodeCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "nerdctl registry containerd",
+		registryAddon: true,
+	},
+	{
+		startMinikube: command.StartKind,
+		bench:         command.RunNerdctlKind,
+		cacheClear:    command.ClearNerdctlAndKindNodeCache,
+		teardown:      command.DeleteKind,
+		provider:      command.ProviderKind,
+		Name:          "nerdctl kind",
+	},
+	{
+		startMinikube: command.StartK3d,
+		bench:         command.RunNerdctlK3d,
+		cacheClear:    command.ClearNerdctlAndK3dNodeCache,
+		teardown:      command.DeleteK3d,
+		provider:      command.ProviderK3d,
+		Name:          "nerdctl k3d",
+	},
+	{
+		startMinikube: command.StartMinikubeImageLoadCrio,
+		bench:         command.RunPodmanImageLoad,
+		cacheClear:    command.ClearPodmanAndMinikubeNodeCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "podman image load crio",
+	},
+	{
+		startMinikube: command.StartMinikubeRegistryPodman,
+		bench:         command.RunPodmanRegistry,
+		cacheClear:    command.ClearRegistryAddonAndPodmanAndMinikubeNodeCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "podman registry crio",
+		registryAddon: true,
 	},
 	{
-		command.StartMinikubeImageBuildDocker,
-		command.RunImageBuild,
-		command.ClearDockerAndMinikubeDockerCache,
-		"image build docker",
+		startMinikube: command.StartKind,
+		bench:         command.RunPodmanKind,
+		cacheClear:    command.ClearPodmanAndKindNodeCache,
+		teardown:      command.DeleteKind,
+		provider:      command.ProviderKind,
+		Name:          "podman kind",
 	},
 	{
-		command.StartMinikubeDockerEnv,
-		command.RunDockerEnv,
-		command.ClearDockerAndMinikubeDockerCache,
-		"docker-env docker",
+		startMinikube: command.StartK3d,
+		bench:         command.RunPodmanK3d,
+		cacheClear:    command.ClearPodmanAndK3dNodeCache,
+		teardown:      command.DeleteK3d,
+		provider:      command.ProviderK3d,
+		Name:          "podman k3d",
 	},
 	{
-		command.StartMinikubeRegistryDocker,
-		command.RunRegistry,
-		command.ClearDockerAndMinikubeDockerCache,
-		"registry docker",
+		startMinikube: command.StartMinikubeCacheAddDocker,
+		bench:         command.RunCacheAdd,
+		cacheClear:    command.ClearCacheAddDockerCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "cache add docker",
+		builder:       command.BuilderDefault,
 	},
 	{
-		command.StartMinikubeImageLoadContainerd,
-		command.RunImageLoad,
-		command.ClearDockerCache,
-		"image load containerd",
+		startMinikube: command.StartMinikubeCacheAddContainerd,
+		bench:         command.RunCacheAdd,
+		cacheClear:    command.ClearCacheAddCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "cache add containerd",
+		builder:       command.BuilderDefault,
 	},
 	{
-		command.StartMinikubeImageBuildContainerd,
-		command.RunImageBuild,
-		command.ClearDockerCache,
-		"image build containerd",
+		startMinikube: command.StartMinikubeCacheAddCrio,
+		bench:         command.RunCacheAdd,
+		cacheClear:    command.ClearCacheAddCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "cache add crio",
+		builder:       command.BuilderDefault,
 	},
 	{
-		command.StartMinikubeDockerEnvContainerd,
-		command.RunDockerEnvWithBuildKitDiabled,
-		func(s string) error { return nil },
-		"docker-env containerd",
+		startMinikube: command.StartMinikubeImagePullDocker,
+		bench:         command.RunImagePull,
+		cacheClear:    command.ClearLocalRegistryAndDockerAndMinikubeDockerCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "image pull docker",
+		builder:       command.BuilderDefault,
 	},
 	{
-		command.StartMinikubeRegistryContainerd,
-		command.RunRegistry,
-		command.ClearDockerCache,
-		"registry containerd",
+		startMinikube: command.StartMinikubeImagePullContainerd,
+		bench:         command.RunImagePull,
+		cacheClear:    command.ClearLocalRegistryAndDockerAndMinikubeNodeCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "image pull containerd",
+		builder:       command.BuilderDefault,
 	},
 	{
-		command.StartMinikubeImageLoadCrio,
-		command.RunImageLoad,
-		command.ClearDockerCache,
-		"image load crio",
+		startMinikube: command.StartMinikubeImagePullCrio,
+		bench:         command.RunImagePull,
+		cacheClear:    command.ClearLocalRegistryAndDockerAndMinikubeNodeCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "image pull crio",
+		builder:       command.BuilderDefault,
 	},
 	{
-		command.StartMinikubeImageBuildCrio,
-		command.RunImageBuild,
-		command.ClearDockerCache,
-		"image build crio",
+		startMinikube: command.StartMinikubePullThroughDocker,
+		bench:         command.RunPullThroughMinikube,
+		cacheClear:    command.ClearPullThroughMinikubeCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "pull-through minikube docker",
+		builder:       command.BuilderDefault,
 	},
 	{
-		command.StartMinikubeRegistryCrio,
-		command.RunRegistry,
-		command.ClearDockerCache,
-		"registry crio",
+		startMinikube: command.StartMinikubePullThroughContainerd,
+		bench:         command.RunPullThroughMinikube,
+		cacheClear:    command.ClearPullThroughMinikubeCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "pull-through minikube containerd",
+		builder:       command.BuilderDefault,
 	},
 	{
-		command.StartKind,
-		command.RunKind,
-		command.ClearKindCache,
-		"kind",
+		startMinikube: command.StartMinikubePullThroughCrio,
+		bench:         command.RunPullThroughMinikube,
+		cacheClear:    command.ClearPullThroughMinikubeCache,
+		teardown:      command.DeleteMinikube,
+		provider:      command.ProviderMinikube,
+		Name:          "pull-through minikube crio",
+		builder:       command.BuilderDefault,
 	},
 	{
-		command.StartK3d,
-		command.RunK3d,
-		command.ClearK3dCache,
-		"k3d",
+		startMinikube: command.StartKindPullThrough,
+		bench:         command.RunPullThroughKind,
+		cacheClear:    command.ClearPullThroughKindCache,
+		teardown:      command.DeleteKind,
+		provider:      command.ProviderKind,
+		Name:          "pull-through kind",
+		builder:       command.BuilderDefault,
 	},
 	{
-		command.StartMicrok8s,
-		command.RunMicrok8s,
-		command.ClearMicrok8sCache,
-		"microk8s local image",
-	}}
+		startMinikube: command.StartK3dPullThrough,
+		bench:         command.RunPullThroughK3d,
+		cacheClear:    command.ClearPullThroughK3dCache,
+		teardown:      command.DeleteK3d,
+		provider:      command.ProviderK3d,
+		Name:          "pull-through k3d",
+		builder:       command.BuilderDefault,
+	},
+}
 
 // Run runs all the benchmarking combinations and returns the average run time and standard deviation for each combination.
-func Run(runs int, config *BenchmarkRunConfig) (AggregatedResultsMatrix, error) {
-	modes := []func(runs int, profile string, image string, method method, imageResults map[string][]float64) error{
+func Run(runs int, config *BenchmarkRunConfig) (*Results, error) {
+	modes := []func(runs int, opts command.RunOptions, image string, method method, imageResults map[string][]command.Timing, imageCells map[string]cellTiming) error{
 		runIterative,
 		runNonIterative,
 	}
 
 	results := runResultsMatrix{}
+	cells := cellTimingMatrix{}
+	startCommands := map[string]string{}
+	clusterTimes := map[string]ClusterTiming{}
+
+	if config.ReuseCluster {
+		for _, m := range BenchMethods {
+			if _, ok := config.BenchMethods[m.Name]; ok && m.registryAddon {
+				log.Printf("skipping %s, the registry addon of a reused cluster can't be emptied between runs as it may hold images that weren't pushed by the benchmark", m.Name)
+			}
+		}
+	}
+
+	if err := checkKubernetesVersions(config); err != nil {
+		return nil, err
+	}
 
 	if err := buildExampleApp(0); err != nil {
 		return nil, err
 	}
 
-	for _, method := range BenchMethods {
-		skipMethod := false
-		if _, ok := config.BenchMethods[method.Name]; !ok {
-			skipMethod = true
-		}
+	if err := command.RecordBaseline(); err != nil {
+		return nil, err
+	}
+
+	imageInfos, err := inspectImages(config)
+	if err != nil {
+		return nil, err
+	}
+
+	methods := expandMethods(config)
+	for _, method := range methods {
+		skipMethod := !config.selected(method)
+		opts := method.runOptions(config)
+		clusterTiming := ClusterTiming{Create: math.NaN(), Ready: math.NaN(), Setup: math.NaN(), Delete: math.NaN()}
 
 		if !skipMethod {
 			// no need to start or delete if this method is completely skipped
-			if err := method.startMinikube(config.Profile, config.MinikubeStartArgs...); err != nil {
-				log.Printf("failed to start %s: %v", method.Name, err)
+			args := append(append([]string{}, config.StartArgs[method.provider]...), method.startArgs...)
+			start := time.Now()
+			clusterStart, err := method.startMinikube(opts, args...)
+			if err != nil {
+				log.Printf("failed to start %s: %v", method.fullName(), err)
+				// the cluster may be up even though the method's setup failed, so it's torn down before the next method starts its own
+				if clusterStart.Command != "" {
+					startCommands[method.fullName()] = clusterStart.Command
+				}
+				clusterTiming.Delete = teardown(method, opts)
+				clusterTimes[method.fullName()] = clusterTiming
 				continue
 			}
+			clusterTiming.Create = clusterStart.Create.Seconds()
+			clusterTiming.Ready = clusterStart.Ready.Seconds()
+			clusterTiming.Setup = (time.Now().Sub(start) - clusterStart.Create - clusterStart.Ready).Seconds()
+			startCommands[method.fullName()] = clusterStart.Command
 		}
 
 		for index, itr := range Iter {
 			for _, image := range Images {
 				imageResults := results[image]
 				if imageResults == nil {
-					imageResults = map[string][]float64{}
+					imageResults = map[string][]command.Timing{}
+				}
+				imageCells := cells[image]
+				if imageCells == nil {
+					imageCells = map[string]cellTiming{}
 				}
 				// check we are going to skip this run
 				skipRun := skipMethod
@@ -215,73 +713,272 @@ func Run(runs int, config *BenchmarkRunConfig) (AggregatedResultsMatrix, error)
 				}
 				if skipRun {
 					// skip this run
-					fmt.Printf("Benchmark %s on %s (%s) is skipped\n", image, method.Name, itr)
+					fmt.Printf("Benchmark %s on %s (%s) is skipped\n", image, method.fullName(), itr)
 				} else {
 					// run this method
-					if err := modes[index](runs, config.Profile, image, method, imageResults); err != nil {
-						log.Printf("failed to run benchmark %s: %v", method.Name, err)
+					if err := modes[index](runs, opts, image, method, imageResults, imageCells); err != nil {
+						log.Printf("failed to run benchmark %s: %v", method.fullName(), err)
 					}
 				}
 				results[image] = imageResults
+				cells[image] = imageCells
 			}
 		}
 
 		if !skipMethod {
-			if err := command.Delete(); err != nil {
-				log.Printf("failed to delete %s: %v", method.Name, err)
+			clusterTiming.Delete = teardown(method, opts)
+			clusterTimes[method.fullName()] = clusterTiming
+		}
+	}
+
+	methodNames := []string{}
+	for _, method := range methods {
+		methodNames = append(methodNames, method.fullName())
+	}
+
+	return &Results{
+		Matrix:        aggregateResults(results, cells, methodNames, imageInfos),
+		Images:        imageInfos,
+		Methods:       methodNames,
+		StartCommands: startCommands,
+		ClusterTimes:  clusterTimes,
+	}, nil
+}
+
+// checkKubernetesVersions returns an error if the provider of a selected method can't run one of the Kubernetes versions,
+// so an unavailable version fails the benchmark before any cluster is started instead of failing every variant using it.
+func checkKubernetesVersions(config *BenchmarkRunConfig) error {
+	checked := map[string]bool{}
+	for _, m := range BenchMethods {
+		if !config.selected(m) || checked[m.provider] {
+			continue
+		}
+		checked[m.provider] = true
+		for _, version := range config.KubernetesVersions {
+			if err := command.CheckKubernetesVersion(m.provider, version); err != nil {
+				return err
 			}
 		}
 	}
+	return nil
+}
+
+// teardown tears down the cluster of the provided method and returns how long it took in seconds, or NaN if it failed.
+func teardown(method method, opts command.RunOptions) float64 {
+	start := time.Now()
+	if err := method.teardown(opts); err != nil {
+		log.Printf("failed to tear down %s: %v", method.fullName(), err)
+		return math.NaN()
+	}
+	return time.Now().Sub(start).Seconds()
+}
 
-	return aggregateResults(results), nil
+// expandMethods crosses every selected method with the configured dimensions.
+// Methods that aren't selected are left as is, so they are still reported as skipped.
+func expandMethods(config *BenchmarkRunConfig) []method {
+	methods := []method{}
+	for _, m := range BenchMethods {
+		if !config.selected(m) {
+			methods = append(methods, m)
+			continue
+		}
+		variants := withNodes([]method{m}, config.Nodes)
+		variants = crossBuilders(variants, config.Builders)
+		variants = crossDrivers(variants, config.Drivers)
+		variants = crossResources(variants, config.Resources)
+		variants = crossKubernetesVersions(variants, config.KubernetesVersions)
+		methods = append(methods, variants...)
+	}
+	return methods
+}
+
+// withNodes starts every multi-node method with the provided number of nodes, if there are multiple.
+func withNodes(methods []method, nodes int) []method {
+	if nodes < 2 {
+		return methods
+	}
+	res := []method{}
+	for _, m := range methods {
+		variant := m.variant(fmt.Sprintf("%d nodes", nodes))
+		variant.startArgs = append(variant.startArgs, fmt.Sprintf("--nodes=%d", nodes))
+		res = append(res, variant)
+	}
+	return res
+}
+
+// crossBuilders crosses every docker based method with the provided builders.
+func crossBuilders(methods []method, builders []command.Builder) []method {
+	res := []method{}
+	for _, m := range methods {
+		if m.builder == "" || len(builders) == 0 {
+			res = append(res, m)
+			continue
+		}
+		for _, builder := range builders {
+			variant := m.variant(string(builder))
+			variant.builder = builder
+			res = append(res, variant)
+		}
+	}
+	return res
+}
+
+// crossDrivers crosses every minikube method with the provided drivers.
+func crossDrivers(methods []method, drivers []string) []method {
+	res := []method{}
+	for _, m := range methods {
+		if m.provider != command.ProviderMinikube || len(drivers) == 0 {
+			res = append(res, m)
+			continue
+		}
+		for _, driver := range drivers {
+			variant := m.variant("driver " + driver)
+			variant.startArgs = append(variant.startArgs, "--driver="+driver)
+			res = append(res, variant)
+		}
+	}
+	return res
+}
+
+// crossResources crosses every minikube method with the provided node resources.
+func crossResources(methods []method, resources []Resources) []method {
+	res := []method{}
+	for _, m := range methods {
+		if m.provider != command.ProviderMinikube || len(resources) == 0 {
+			res = append(res, m)
+			continue
+		}
+		for _, r := range resources {
+			variant := m.variant(r.labels()...)
+			variant.startArgs = append(variant.startArgs, r.startArgs()...)
+			res = append(res, variant)
+		}
+	}
+	return res
+}
+
+// crossKubernetesVersions crosses every method whose provider supports selecting a Kubernetes version with the provided versions.
+func crossKubernetesVersions(methods []method, versions []string) []method {
+	res := []method{}
+	for _, m := range methods {
+		if len(versions) == 0 || command.KubernetesVersionArgs(m.provider, versions[0]) == nil {
+			res = append(res, m)
+			continue
+		}
+		for _, version := range versions {
+			variant := m.variant("kubernetes " + version)
+			variant.startArgs = append(variant.startArgs, command.KubernetesVersionArgs(m.provider, version)...)
+			res = append(res, variant)
+		}
+	}
+	return res
+}
+
+// variant returns a copy of the method with the provided labels added.
+func (m method) variant(labels ...string) method {
+	v := m
+	v.labels = append(append([]string{}, m.labels...), labels...)
+	v.startArgs = append([]string{}, m.startArgs...)
+	return v
+}
+
+// inspectImages builds every selected image once and records its size, layer count and image ID.
+// The Docker cache is cleared afterwards so the first benchmark run isn't warmed up by the inspection build.
+// Inspecting requires Docker, if an image can't be inspected its metadata is left out of the results.
+func inspectImages(config *BenchmarkRunConfig) (map[string]command.ImageInfo, error) {
+	infos := map[string]command.ImageInfo{}
+	for _, image := range Images {
+		if _, ok := config.Images[image]; !ok {
+			continue
+		}
+		fmt.Printf("Inspecting %s\n", image)
+		info, err := command.InspectImage(image)
+		if err != nil {
+			log.Printf("failed to inspect %s: %v", image, err)
+			continue
+		}
+		infos[image] = info
+	}
+	if len(infos) == 0 {
+		return infos, nil
+	}
+	if err := command.PruneDocker(); err != nil {
+		return nil, err
+	}
+	return infos, nil
 }
 
 // runIterative runs a benchmark using the iteratvie flow, which means changing the binary in between each run,
 // mimicing an iterative flow, the cache is cleared once all the runs are complete.
-func runIterative(runs int, profile string, image string, method method, imageResults map[string][]float64) error {
-	name := method.Name + Iter[0]
+func runIterative(runs int, opts command.RunOptions, image string, method method, imageResults map[string][]command.Timing, imageCells map[string]cellTiming) error {
+	name := method.fullName() + Iter[0]
 	fmt.Printf("\nRunning %s on %s\n", image, name)
+	start := time.Now()
+	cell := cellTiming{}
+	defer func() {
+		cell.Wall = time.Now().Sub(start).Seconds()
+		imageCells[name] = cell
+	}()
 	for i := 0; i < runs; i++ {
 		if err := buildExampleApp(i); err != nil {
 			return err
 		}
-		runTime, err := method.bench(image, profile)
+		runTime, err := method.bench(image, opts)
 		if err != nil {
 			return fmt.Errorf("failed running benchmark %s on %s: %v", image, name, err)
 		}
-		displayRun(i+1, runTime)
+		displayRun(i+1, runTime.Total)
 		if i == 0 {
 			continue
 		}
 		imageResults[name] = append(imageResults[name], runTime)
 	}
-	if err := method.cacheClear(profile); err != nil {
-		return fmt.Errorf("failed to clear cache: %v", err)
+	clear, err := clearCache(method, opts)
+	if err != nil {
+		return err
 	}
+	cell.CacheClears = append(cell.CacheClears, clear)
 
 	return nil
 }
 
 // runNonIterative runs a branchmark using the non-iterative flow, which means clearing the cache after each run,
 // idealy starting fresh everytime.
-func runNonIterative(runs int, profile string, image string, method method, imageResults map[string][]float64) error {
-	name := method.Name + Iter[1]
+func runNonIterative(runs int, opts command.RunOptions, image string, method method, imageResults map[string][]command.Timing, imageCells map[string]cellTiming) error {
+	name := method.fullName() + Iter[1]
 	fmt.Printf("\nRunning %s on %s\n", image, name)
+	start := time.Now()
+	cell := cellTiming{}
+	defer func() {
+		cell.Wall = time.Now().Sub(start).Seconds()
+		imageCells[name] = cell
+	}()
 	for i := 0; i < runs; i++ {
-		runTime, err := method.bench(image, profile)
+		runTime, err := method.bench(image, opts)
 		if err != nil {
 			return fmt.Errorf("failed running benchmark %s on %s: %v", image, name, err)
 		}
 		imageResults[name] = append(imageResults[name], runTime)
-		displayRun(i+1, runTime)
-		if err := method.cacheClear(profile); err != nil {
-			return fmt.Errorf("failed to clear cache: %v", err)
+		displayRun(i+1, runTime.Total)
+		clear, err := clearCache(method, opts)
+		if err != nil {
+			return err
 		}
+		cell.CacheClears = append(cell.CacheClears, clear)
 	}
 
 	return nil
 }
 
+// clearCache clears the cache of the provided method and returns how long it took in seconds.
+func clearCache(method method, opts command.RunOptions) (float64, error) {
+	start := time.Now()
+	if err := method.cacheClear(opts); err != nil {
+		return 0, fmt.Errorf("failed to clear cache: %v", err)
+	}
+	return time.Now().Sub(start).Seconds(), nil
+}
+
 // buildExampleApp builds the example app and sets the ldflag using the provided num.
 // This allows the app the easily be changed, helping mimic the iterative workflow.
 func buildExampleApp(num int) error {
@@ -294,29 +991,52 @@ func buildExampleApp(num int) error {
 	return nil
 }
 
-// aggregateResults calculates the average and standard deviation from the run results
-func aggregateResults(r runResultsMatrix) AggregatedResultsMatrix {
+// aggregateResults calculates the average, standard deviation, throughput and time breakdown from the run results
+func aggregateResults(r runResultsMatrix, cells cellTimingMatrix, methodNames []string, imageInfos map[string]command.ImageInfo) AggregatedResultsMatrix {
 	ag := AggregatedResultsMatrix{}
 	for _, image := range Images {
 		imageResults := map[string]aggregatedRunResult{}
-		for _, method := range BenchMethods {
+		for _, methodName := range methodNames {
 			for _, iter := range Iter {
-				runs := r[image][method.Name+iter]
-				var sum, std, count float64
+				runs := r[image][methodName+iter]
+				var sum, transferSum, saveSum, std, count float64
 				for _, run := range runs {
-					sum += run
+					sum += run.Total
+					transferSum += run.Transfer
+					saveSum += run.Save
 					count++
 				}
 				avg := sum / count
 				for _, run := range runs {
-					std += math.Pow(run-avg, 2)
+					std += math.Pow(run.Total-avg, 2)
 				}
 				std = math.Sqrt(std / count)
+				cell, ran := cells[image][methodName+iter]
+				var clearSum float64
+				for _, clear := range cell.CacheClears {
+					clearSum += clear
+				}
+				// combinations that weren't run have no time breakdown
+				wall, measured := cell.Wall, sum
+				if !ran {
+					wall, measured = math.NaN(), math.NaN()
+				}
+				// in the iterative flow only the changed layers are transferred after the first run, rather than the whole image
+				tput := math.NaN()
+				if iter == Iter[1] {
+					tput = throughput(imageInfos[image].Size, transferSum/count)
+				}
 				agr := aggregatedRunResult{
-					Avg: avg,
-					Std: std,
+					Avg:           avg,
+					Std:           std,
+					Throughput:    tput,
+					SaveAvg:       saveSum / count,
+					CacheClearAvg: clearSum / float64(len(cell.CacheClears)),
+					Wall:          wall,
+					Measured:      measured,
+					Overhead:      wall - measured,
 				}
-				imageResults[method.Name+iter] = agr
+				imageResults[methodName+iter] = agr
 			}
 		}
 		ag[image] = imageResults
@@ -324,6 +1044,14 @@ func aggregateResults(r runResultsMatrix) AggregatedResultsMatrix {
 	return ag
 }
 
+// throughput returns the rate in MB/s at which the provided number of bytes were moved in the provided number of seconds.
+func throughput(bytes int64, seconds float64) float64 {
+	if bytes == 0 || seconds == 0 {
+		return math.NaN()
+	}
+	return float64(bytes) / 1e6 / seconds
+}
+
 func displayRun(runNum int, runTime float64) {
 	fmt.Printf("Run #%d  took %.2f seconds\n", runNum, runTime)
 }
@@ -0,0 +1,217 @@
+package benchmark
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"benchmark/pkg/command"
+)
+
+// variants returns the full name, followed by the start args if there are any, of every provided method whose name is
+// one of the provided names.
+func variants(methods []method, names ...string) []string {
+	res := []string{}
+	for _, m := range methods {
+		for _, name := range names {
+			if m.Name == name {
+				v := m.fullName()
+				if len(m.startArgs) != 0 {
+					v += ": " + strings.Join(m.startArgs, " ")
+				}
+				res = append(res, v)
+			}
+		}
+	}
+	return res
+}
+
+func TestResourceSweep(t *testing.T) {
+	tests := []struct {
+		name      string
+		memory    []string
+		cpus      []string
+		diskSizes []string
+		want      []Resources
+	}{
+		{
+			name: "no values",
+		},
+		{
+			name:   "single dimension",
+			memory: []string{"2g", "4g"},
+			want:   []Resources{{Memory: "2g"}, {Memory: "4g"}},
+		},
+		{
+			name:      "every dimension",
+			memory:    []string{"2g", "4g"},
+			cpus:      []string{"2", "4"},
+			diskSizes: []string{"20g"},
+			want: []Resources{
+				{Memory: "2g", CPUs: "2", DiskSize: "20g"},
+				{Memory: "2g", CPUs: "4", DiskSize: "20g"},
+				{Memory: "4g", CPUs: "2", DiskSize: "20g"},
+				{Memory: "4g", CPUs: "4", DiskSize: "20g"},
+			},
+		},
+		{
+			name:      "dimensions left to the default",
+			cpus:      []string{"2"},
+			diskSizes: []string{"20g", "40g"},
+			want:      []Resources{{CPUs: "2", DiskSize: "20g"}, {CPUs: "2", DiskSize: "40g"}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ResourceSweep(tc.memory, tc.cpus, tc.diskSizes); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ResourceSweep() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCrossResources(t *testing.T) {
+	methods := []method{
+		{Name: "minikube", provider: command.ProviderMinikube, startArgs: []string{"--container-runtime=containerd"}},
+		{Name: "kind", provider: command.ProviderKind},
+	}
+	tests := []struct {
+		name      string
+		resources []Resources
+		want      []string
+	}{
+		{
+			name: "no resources",
+			want: []string{"minikube: --container-runtime=containerd", "kind"},
+		},
+		{
+			name:      "minikube methods are crossed",
+			resources: []Resources{{Memory: "2g"}, {Memory: "4g", CPUs: "2", DiskSize: "20g"}},
+			want: []string{
+				"minikube (memory 2g): --container-runtime=containerd --memory=2g",
+				"minikube (memory 4g, cpus 2, disk 20g): --container-runtime=containerd --memory=4g --cpus=2 --disk-size=20g",
+				"kind",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := variants(crossResources(methods, tc.resources), "minikube", "kind")
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("crossResources() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCrossKubernetesVersions(t *testing.T) {
+	methods := []method{
+		{Name: "minikube", provider: command.ProviderMinikube},
+		{Name: "kind", provider: command.ProviderKind},
+		{Name: "k3d", provider: command.ProviderK3d},
+		{Name: "microk8s", provider: command.ProviderMicrok8s},
+	}
+	tests := []struct {
+		name     string
+		versions []string
+		want     []string
+	}{
+		{
+			name: "no versions",
+			want: []string{"minikube", "kind", "k3d", "microk8s"},
+		},
+		{
+			name:     "versions with and without the v prefix",
+			versions: []string{"1.27.3", "v1.28.0"},
+			want: []string{
+				"minikube (kubernetes 1.27.3): --kubernetes-version=v1.27.3",
+				"minikube (kubernetes v1.28.0): --kubernetes-version=v1.28.0",
+				"kind (kubernetes 1.27.3): --image kindest/node:v1.27.3",
+				"kind (kubernetes v1.28.0): --image kindest/node:v1.28.0",
+				"k3d (kubernetes 1.27.3): --image rancher/k3s:v1.27.3-k3s1",
+				"k3d (kubernetes v1.28.0): --image rancher/k3s:v1.28.0-k3s1",
+				"microk8s",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := variants(crossKubernetesVersions(methods, tc.versions), "minikube", "kind", "k3d", "microk8s")
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("crossKubernetesVersions() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExpandMethods(t *testing.T) {
+	tests := []struct {
+		name   string
+		config BenchmarkRunConfig
+		// methods are the selected methods, the variants of which are checked.
+		methods []string
+		want    []string
+	}{
+		{
+			name:    "no dimensions",
+			methods: []string{"kind", "image build docker"},
+			want:    []string{"image build docker", "kind"},
+		},
+		{
+			name:    "builders and drivers",
+			config:  BenchmarkRunConfig{Builders: []command.Builder{command.BuilderLegacy, command.BuilderBuildx}, Drivers: []string{"docker", "kvm2"}},
+			methods: []string{"image load docker", "image build docker", "kind"},
+			want: []string{
+				"image load docker (legacy, driver docker): --driver=docker",
+				"image load docker (legacy, driver kvm2): --driver=kvm2",
+				"image load docker (buildx, driver docker): --driver=docker",
+				"image load docker (buildx, driver kvm2): --driver=kvm2",
+				"image build docker (driver docker): --driver=docker",
+				"image build docker (driver kvm2): --driver=kvm2",
+				"kind (legacy)",
+				"kind (buildx)",
+			},
+		},
+		{
+			name:    "resources and kubernetes versions",
+			config:  BenchmarkRunConfig{Resources: []Resources{{Memory: "2g"}}, KubernetesVersions: []string{"1.28.0"}},
+			methods: []string{"image build docker", "kind", "microk8s local image"},
+			want: []string{
+				"image build docker (memory 2g, kubernetes 1.28.0): --memory=2g --kubernetes-version=v1.28.0",
+				"kind (kubernetes 1.28.0): --image kindest/node:v1.28.0",
+				"microk8s local image",
+			},
+		},
+		{
+			name:    "multiple nodes leave the single node methods as is",
+			config:  BenchmarkRunConfig{Nodes: 2, Drivers: []string{"docker"}},
+			methods: []string{"image load archive docker", "image build docker"},
+			want: []string{
+				"image load archive docker",
+				"image build docker (2 nodes, driver docker): --nodes=2 --driver=docker",
+			},
+		},
+		{
+			name:    "reused cluster leaves the registry addon methods as is",
+			config:  BenchmarkRunConfig{ReuseCluster: true, Drivers: []string{"docker"}},
+			methods: []string{"registry docker", "image build docker"},
+			want: []string{
+				"image build docker (driver docker): --driver=docker",
+				"registry docker",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			config := tc.config
+			config.BenchMethods = map[string]struct{}{}
+			for _, m := range tc.methods {
+				config.BenchMethods[m] = struct{}{}
+			}
+			got := variants(expandMethods(&config), tc.methods...)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expandMethods() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
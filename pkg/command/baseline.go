@@ -0,0 +1,312 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// benchmarkLabel is set on every image built by the benchmark, so it can be told apart from the user's images.
+const benchmarkLabel = "benchmark=true"
+
+// baseline contains the images and build cache that existed before the benchmark was run, which are never removed.
+var baseline = struct {
+	recorded bool
+	// images contains the names of the existing images of every image tool.
+	images map[string]map[string]bool
+	// buildCache contains the IDs of the existing Docker build cache records.
+	buildCache map[string]bool
+	// buildkitCache contains the IDs of the existing cache records of the BuildKit daemon nerdctl builds with,
+	// it's nil if BuildKit wasn't reachable.
+	buildkitCache map[string]bool
+	// danglingImages contains the IDs of the existing dangling podman images.
+	danglingImages map[string]bool
+}{}
+
+// imageTools are the tools whose images are recorded in the baseline.
+var imageTools = []string{"docker", nerdctl.name, podman.name}
+
+// RecordBaseline records the images and build cache that exist before the benchmark is run,
+// so clearing the cache only removes what was created by the benchmark.
+func RecordBaseline() error {
+	baseline.images = map[string]map[string]bool{}
+	for _, tool := range imageTools {
+		images, err := listImages(tool)
+		if err != nil {
+			return err
+		}
+		baseline.images[tool] = map[string]bool{}
+		for _, i := range images {
+			baseline.images[tool][normalizeImage(i)] = true
+		}
+	}
+
+	ids, err := listBuildCache()
+	if err != nil {
+		return err
+	}
+	baseline.buildCache = set(ids)
+
+	// BuildKit isn't needed unless the nerdctl methods are run, so its cache is only protected if it's reachable
+	baseline.buildkitCache = nil
+	if ids, err := listBuildkitCache(); err == nil {
+		baseline.buildkitCache = set(ids)
+	}
+
+	ids, err = listDanglingImages()
+	if err != nil {
+		return err
+	}
+	baseline.danglingImages = set(ids)
+
+	baseline.recorded = true
+	return nil
+}
+
+// listImages returns the repository and tag of every image of the provided tool, tools that aren't installed have no images.
+func listImages(tool string) ([]string, error) {
+	if _, err := exec.LookPath(tool); err != nil {
+		return nil, nil
+	}
+	o, err := output(exec.Command(tool, "image", "ls", "--format", "{{.Repository}}:{{.Tag}}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s images: %v", tool, err)
+	}
+	return lines(o), nil
+}
+
+// listBuildCache returns the IDs of every Docker build cache record.
+func listBuildCache() ([]string, error) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return nil, nil
+	}
+	o, err := output(exec.Command("docker", "system", "df", "-v", "--format", "{{json .}}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list docker build cache: %v", err)
+	}
+	var df struct {
+		BuildCache []struct {
+			ID string
+		}
+	}
+	if err := json.Unmarshal([]byte(o), &df); err != nil {
+		return nil, fmt.Errorf("failed to parse docker build cache: %v", err)
+	}
+	ids := []string{}
+	for _, r := range df.BuildCache {
+		ids = append(ids, r.ID)
+	}
+	return ids, nil
+}
+
+// listBuildkitCache returns the IDs of every cache record of the BuildKit daemon nerdctl builds with.
+func listBuildkitCache() ([]string, error) {
+	if _, err := exec.LookPath("buildctl"); err != nil {
+		return nil, nil
+	}
+	o, err := output(exec.Command("buildctl", "du", "-v"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list buildkit cache: %v", err)
+	}
+	ids := []string{}
+	for _, l := range lines(o) {
+		if strings.HasPrefix(l, "ID:") {
+			ids = append(ids, strings.TrimSpace(strings.TrimPrefix(l, "ID:")))
+		}
+	}
+	return ids, nil
+}
+
+// listDanglingImages returns the IDs of every dangling podman image, which includes the intermediate images podman caches.
+func listDanglingImages() ([]string, error) {
+	if _, err := exec.LookPath("podman"); err != nil {
+		return nil, nil
+	}
+	o, err := output(exec.Command("podman", "image", "ls", "-a", "-q", "--no-trunc", "--filter", "dangling=true"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dangling podman images: %v", err)
+	}
+	return lines(o), nil
+}
+
+// set returns a set containing the provided values.
+func set(values []string) map[string]bool {
+	s := map[string]bool{}
+	for _, v := range values {
+		s[v] = true
+	}
+	return s
+}
+
+// normalizeImage strips the default registry from the provided image name, as not every tool includes it.
+func normalizeImage(image string) string {
+	image = strings.TrimPrefix(image, "docker.io/")
+	return strings.TrimPrefix(image, "library/")
+}
+
+// baseImages returns the images the benchmark images are built from.
+func baseImages() ([]string, error) {
+	dockerfiles, err := filepath.Glob("testdata/Dockerfile.*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Dockerfiles: %v", err)
+	}
+	seen := map[string]bool{}
+	images := []string{}
+	for _, dockerfile := range dockerfiles {
+		b, err := os.ReadFile(dockerfile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", dockerfile, err)
+		}
+		for _, l := range lines(string(b)) {
+			fields := strings.Fields(l)
+			if len(fields) < 2 || !strings.EqualFold(fields[0], "FROM") || seen[fields[1]] {
+				continue
+			}
+			seen[fields[1]] = true
+			images = append(images, fields[1])
+		}
+	}
+	return images, nil
+}
+
+// pruneImages removes the images of the provided tool that were created by the benchmark, those are the images
+// labeled by the benchmark along with any base image of the benchmark images that didn't exist before the benchmark.
+func pruneImages(tool string) error {
+	if !baseline.recorded {
+		return fmt.Errorf("baseline wasn't recorded, refusing to remove %s images", tool)
+	}
+
+	c := exec.Command(tool, "image", "prune", "-a", "-f", "--filter", "label="+benchmarkLabel)
+	if _, err := run(c); err != nil {
+		return fmt.Errorf("failed to prune %s images: %v", tool, err)
+	}
+
+	bases, err := baseImages()
+	if err != nil {
+		return err
+	}
+	existing, err := listImages(tool)
+	if err != nil {
+		return err
+	}
+	for _, i := range existing {
+		for _, base := range bases {
+			if normalizeImage(i) != normalizeImage(base) || baseline.images[tool][normalizeImage(i)] {
+				continue
+			}
+			rmi := exec.Command(tool, "rmi", i)
+			if _, err := run(rmi); err != nil {
+				return fmt.Errorf("failed to remove %s image: %v", tool, err)
+			}
+		}
+	}
+	return nil
+}
+
+// pruneBuildCache removes the Docker build cache records that were created by the benchmark.
+// Build cache records can't be labeled, so every record that didn't exist before the benchmark is removed.
+func pruneBuildCache() error {
+	if !baseline.recorded {
+		return fmt.Errorf("baseline wasn't recorded, refusing to remove docker build cache")
+	}
+
+	created, err := createdBuildCache()
+	if err != nil || len(created) == 0 {
+		return err
+	}
+	// the id filter only accepts a single value, which BuildKit matches as a regular expression when quoted with slashes
+	filter := fmt.Sprintf("id=/^(%s)/", strings.Join(created, "|"))
+	c := exec.Command("docker", "builder", "prune", "-a", "-f", "--filter", filter)
+	if _, err := run(c); err != nil {
+		return fmt.Errorf("failed to prune docker build cache: %v", err)
+	}
+
+	// verify
+	left, err := createdBuildCache()
+	if err != nil {
+		return err
+	}
+	if len(left) != 0 {
+		return fmt.Errorf("%d docker build cache records created by the benchmark are left after pruning", len(left))
+	}
+	return nil
+}
+
+// createdBuildCache returns the IDs of the Docker build cache records that didn't exist before the benchmark.
+func createdBuildCache() ([]string, error) {
+	ids, err := listBuildCache()
+	if err != nil {
+		return nil, err
+	}
+	return created(ids, baseline.buildCache), nil
+}
+
+// pruneBuildkitCache removes the cache records of the BuildKit daemon nerdctl builds with that were created by the benchmark.
+func pruneBuildkitCache() error {
+	if !baseline.recorded || baseline.buildkitCache == nil {
+		return fmt.Errorf("buildkit cache wasn't recorded before the benchmark, refusing to remove it")
+	}
+
+	ids, err := listBuildkitCache()
+	if err != nil {
+		return err
+	}
+	ids = created(ids, baseline.buildkitCache)
+	if len(ids) == 0 {
+		return nil
+	}
+	// records matching any of the filters are removed
+	args := []string{"prune", "--all"}
+	for _, id := range ids {
+		args = append(args, "--filter", "id=="+id)
+	}
+	if _, err := run(exec.Command("buildctl", args...)); err != nil {
+		return fmt.Errorf("failed to prune buildkit cache: %v", err)
+	}
+
+	// verify
+	left, err := listBuildkitCache()
+	if err != nil {
+		return err
+	}
+	if left = created(left, baseline.buildkitCache); len(left) != 0 {
+		return fmt.Errorf("%d buildkit cache records created by the benchmark are left after pruning", len(left))
+	}
+	return nil
+}
+
+// pruneDanglingImages removes the dangling podman images that were created by the benchmark.
+// Removing an intermediate image leaves its parent dangling, so they're removed until none is left.
+func pruneDanglingImages() error {
+	if !baseline.recorded {
+		return fmt.Errorf("baseline wasn't recorded, refusing to remove dangling podman images")
+	}
+
+	for {
+		ids, err := listDanglingImages()
+		if err != nil {
+			return err
+		}
+		ids = created(ids, baseline.danglingImages)
+		if len(ids) == 0 {
+			return nil
+		}
+		if _, err := run(exec.Command("podman", append([]string{"rmi"}, ids...)...)); err != nil {
+			return fmt.Errorf("failed to remove dangling podman images: %v", err)
+		}
+	}
+}
+
+// created returns the provided IDs that aren't in the existing ones.
+func created(ids []string, existing map[string]bool) []string {
+	res := []string{}
+	for _, id := range ids {
+		if !existing[id] {
+			res = append(res, id)
+		}
+	}
+	return res
+}
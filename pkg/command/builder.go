@@ -0,0 +1,115 @@
+package command
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Builder is the Docker builder used to build the benchmark images.
+type Builder string
+
+const (
+	// BuilderDefault leaves the choice of builder to Docker.
+	BuilderDefault Builder = "default"
+	// BuilderLegacy is the legacy Docker builder.
+	BuilderLegacy Builder = "legacy"
+	// BuilderBuildKit is BuildKit built into the Docker daemon.
+	BuilderBuildKit Builder = "buildkit"
+	// BuilderBuildx is buildx with the docker-container driver, which runs BuildKit in its own container.
+	BuilderBuildx Builder = "buildx"
+)
+
+// Builders is the list of builders that can be selected.
+var Builders = []Builder{BuilderLegacy, BuilderBuildKit, BuilderBuildx}
+
+const (
+	// buildxHostInstance is the name of the buildx builder instance used for builds on the host.
+	buildxHostInstance = "benchmark-buildx"
+	// buildxDockerEnvInstance is the name of the buildx builder instance used for builds via docker-env.
+	buildxDockerEnvInstance = "benchmark-buildx-env"
+)
+
+// buildCommand returns the shell command that builds the provided image with the provided tag.
+// instance is the name of the buildx builder instance to use, which is created if it doesn't exist yet.
+func (b Builder) buildCommand(tag string, image string, instance string) string {
+	dockerfile := fmt.Sprintf("testdata/Dockerfile.%s", image)
+	switch b {
+	case BuilderLegacy:
+		return fmt.Sprintf("DOCKER_BUILDKIT=0 docker build --label %s -t %s -f %s .", benchmarkLabel, tag, dockerfile)
+	case BuilderBuildKit:
+		return fmt.Sprintf("DOCKER_BUILDKIT=1 docker build --label %s -t %s -f %s .", benchmarkLabel, tag, dockerfile)
+	case BuilderBuildx:
+		driverOpts := ""
+		if network := sandboxRunArgs(); instance == buildxHostInstance && network != nil {
+			// the BuildKit container has no network otherwise, as the isolated Docker daemon has no default bridge network
+			driverOpts = " --driver-opt network=" + network[1]
+		}
+		create := fmt.Sprintf("(docker buildx inspect %[1]s > /dev/null 2>&1 || docker buildx create --name %[1]s --driver docker-container%[2]s > /dev/null)", instance, driverOpts)
+		return fmt.Sprintf("%s && docker buildx build --builder %s --load --label %s -t %s -f %s .", create, instance, benchmarkLabel, tag, dockerfile)
+	default:
+		return fmt.Sprintf("docker build --label %s -t %s -f %s .", benchmarkLabel, tag, dockerfile)
+	}
+}
+
+// dockerBuild returns the command that builds the provided image with the provided tag on the host.
+func dockerBuild(builder Builder, tag string, image string) *exec.Cmd {
+	return exec.Command("/bin/bash", "-c", builder.buildCommand(tag, image, buildxHostInstance))
+}
+
+// clearBuilderCache removes the buildx builder instance, along with its cache, as it isn't cleared by a docker system prune.
+// env is prepended to the command to point Docker at the daemon the instance was created on.
+func clearBuilderCache(builder Builder, instance string, env string) error {
+	if builder != BuilderBuildx {
+		return nil
+	}
+	args := fmt.Sprintf("%s if docker buildx inspect %[2]s > /dev/null 2>&1; then docker buildx rm %[2]s; fi", env, instance)
+	c := exec.Command("/bin/bash", "-c", args)
+	if _, err := run(c); err != nil {
+		return fmt.Errorf("failed to remove buildx builder: %v", err)
+	}
+	return nil
+}
+
+// deleteBuildxHostInstance removes the buildx builder instance used for builds on the host, along with its BuildKit
+// container and state volume, which are left behind if the benchmark was killed before clearing the builder cache.
+func deleteBuildxHostInstance() error {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return nil
+	}
+	if err := clearBuilderCache(BuilderBuildx, buildxHostInstance, ""); err != nil {
+		return err
+	}
+
+	o, err := output(exec.Command("docker", "ps", "-a", "--format", "{{.Names}}"))
+	if err != nil {
+		return fmt.Errorf("failed to list docker containers: %v", err)
+	}
+	for _, name := range lines(o) {
+		if isBuildxHostNode(name) {
+			if _, err := run(exec.Command("docker", "rm", "-f", name)); err != nil {
+				return fmt.Errorf("failed to remove buildx container: %v", err)
+			}
+		}
+	}
+	o, err = output(exec.Command("docker", "volume", "ls", "-q"))
+	if err != nil {
+		return fmt.Errorf("failed to list docker volumes: %v", err)
+	}
+	for _, name := range lines(o) {
+		if isBuildxHostNode(strings.TrimSuffix(name, "_state")) {
+			if _, err := run(exec.Command("docker", "volume", "rm", "-f", name)); err != nil {
+				return fmt.Errorf("failed to remove buildx volume: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// isBuildxHostNode returns whether the provided container is a node of the host buildx builder instance,
+// buildx names those buildx_buildkit_<instance><index> and their volumes <container>_state.
+func isBuildxHostNode(name string) bool {
+	prefix := "buildx_buildkit_" + buildxHostInstance
+	index := strings.TrimPrefix(name, prefix)
+	return strings.HasPrefix(name, prefix) && index != "" && strings.Trim(index, "0123456789") == ""
+}
@@ -0,0 +1,85 @@
+package command
+
+import (
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// cacheAddImage is the image added to minikube's cache by the cache add method.
+const cacheAddImage = localRegistry + "/benchmark-cache-add"
+
+// StartMinikubeCacheAddDocker starts minikube for docker cache add.
+func StartMinikubeCacheAddDocker(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startMinikubeWithLocalRegistry(opts, "docker", args...)
+}
+
+// StartMinikubeCacheAddContainerd starts minikube for containerd cache add.
+func StartMinikubeCacheAddContainerd(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startMinikubeWithLocalRegistry(opts, "containerd", args...)
+}
+
+// StartMinikubeCacheAddCrio starts minikube for crio cache add.
+func StartMinikubeCacheAddCrio(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startMinikubeWithLocalRegistry(opts, "cri-o", args...)
+}
+
+// RunCacheAdd builds the provided image, pushes it to the local registry, adds it to minikube's cache and returns the run time.
+func RunCacheAdd(image string, opts RunOptions) (Timing, error) {
+	build, push, id, err := pushToLocalRegistry(image, "benchmark-cache-add", opts)
+	if err != nil {
+		return Timing{}, fmt.Errorf("failed to prepare cache add: %v", err)
+	}
+	// cache add keeps the image already in the cache instead of the pushed one, so it's removed from the cache of the previous run
+	if err := minikubeCacheDelete(opts.Profile); err != nil {
+		return Timing{}, err
+	}
+
+	// cache add
+	cacheAdd := exec.Command("./minikube", "-p", opts.Profile, "cache", "add", cacheAddImage)
+	start := time.Now()
+	if _, err := run(cacheAdd); err != nil {
+		return Timing{}, fmt.Errorf("failed to cache add: %v", err)
+	}
+	transfer := push + time.Now().Sub(start)
+
+	// verify
+	if err := verifyImage("benchmark-cache-add", opts.Profile); err != nil {
+		return Timing{}, fmt.Errorf("image was not found after cache add: %v", err)
+	}
+	loaded, err := crictlImageID(NewMinikubeProvider(opts.Profile), cacheAddImage)
+	if err != nil {
+		return Timing{}, err
+	}
+	if loaded != id {
+		return Timing{}, fmt.Errorf("cache add loaded image %s instead of the pushed image %s", loaded, id)
+	}
+
+	return Timing{Total: (build + transfer).Seconds(), Transfer: transfer.Seconds()}, nil
+}
+
+// minikubeCacheDelete removes the cache add image from minikube's cache of the provided profile, which lives on the host.
+func minikubeCacheDelete(profile string) error {
+	args := fmt.Sprintf("if ./minikube -p %[1]s cache list | grep %[2]s; then ./minikube -p %[1]s cache delete %[2]s; fi", profile, cacheAddImage)
+	c := exec.Command("/bin/bash", "-c", args)
+	if _, err := run(c); err != nil {
+		return fmt.Errorf("failed to delete image from minikube cache: %v", err)
+	}
+	return nil
+}
+
+// ClearCacheAddDockerCache clears out caching related to the docker cache add method.
+func ClearCacheAddDockerCache(opts RunOptions) error {
+	if err := minikubeCacheDelete(opts.Profile); err != nil {
+		return err
+	}
+	return ClearLocalRegistryAndDockerAndMinikubeDockerCache(opts)
+}
+
+// ClearCacheAddCache clears out caching related to the containerd and crio cache add methods.
+func ClearCacheAddCache(opts RunOptions) error {
+	if err := minikubeCacheDelete(opts.Profile); err != nil {
+		return err
+	}
+	return ClearLocalRegistryAndDockerAndMinikubeNodeCache(opts)
+}
@@ -2,10 +2,35 @@
 package command
 
 import (
+	"bytes"
 	"fmt"
 	"os/exec"
+	"strings"
 )
 
+// Timing contains the run time of a single benchmark run in seconds.
+type Timing struct {
+	// Total is the time taken by the whole run.
+	Total float64
+	// Transfer is the time spent moving the built image into the cluster.
+	// Methods that build the image inside the cluster have no separate transfer, so it equals Total.
+	Transfer float64
+	// Save is the time spent saving the built image to an archive before transferring it, it isn't part of Transfer.
+	Save float64
+}
+
+// RunOptions contains the options a benchmark run is carried out with.
+type RunOptions struct {
+	Profile    string
+	Builder    Builder
+	ImageBuild ImageBuildOptions
+	// Nodes is the number of nodes of the minikube cluster, the methods supporting multiple nodes make the image available on all of them.
+	Nodes int
+	// ReuseCluster is set when the existing cluster is reused instead of being started and deleted by the benchmark,
+	// in which case starting it only validates that it exists and runs the expected runtime, and deleting it does nothing.
+	ReuseCluster bool
+}
+
 // run simply runs the command and returns the output, if the command fails it returns a detailed error message.
 func run(cmd *exec.Cmd) (string, error) {
 	o, err := cmd.CombinedOutput()
@@ -15,10 +40,46 @@ func run(cmd *exec.Cmd) (string, error) {
 	return string(o), nil
 }
 
-func Delete() error {
-	if err := deleteMinikube(); err != nil {
+// output runs the command and returns only its standard output, if the command fails it returns a detailed error message.
+func output(cmd *exec.Cmd) (string, error) {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	o, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("\ncommand: %s\ncommand output: %s%s\nerr: %v", cmd.String(), string(o), stderr.String(), err)
+	}
+	return string(o), nil
+}
+
+// lines splits the provided output into its non empty lines.
+func lines(o string) []string {
+	res := []string{}
+	for _, l := range strings.Split(o, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			res = append(res, l)
+		}
+	}
+	return res
+}
+
+// Cleanup removes any leftover benchmark cluster of every provider along with the containers and files created by the benchmark.
+// microk8s isn't stopped, as it isn't created by the benchmark.
+func Cleanup(opts RunOptions) error {
+	if err := DeleteMinikube(opts); err != nil {
+		return err
+	}
+
+	if err := DeleteKind(opts); err != nil {
+		return err
+	}
+
+	if err := DeleteK3d(opts); err != nil {
+		return err
+	}
+
+	if err := deleteBuildxHostInstance(); err != nil {
 		return err
 	}
 
-	return deleteKind()
+	return deleteRegistryConfigs()
 }
@@ -2,29 +2,42 @@ package command
 
 import (
 	"fmt"
-	"os/exec"
 )
 
-// DockerSystemPrune does a docker system prune
-func DockerSystemPrune() error {
-	c := exec.Command("docker", "system", "prune", "-a", "--volumes", "-f")
-	if _, err := run(c); err != nil {
-		return fmt.Errorf("failed to docker prune: %v", err)
+// PruneDocker removes the images and build cache created by the benchmark from Docker.
+func PruneDocker() error {
+	if err := pruneImages("docker"); err != nil {
+		return err
 	}
-	return nil
+	return pruneBuildCache()
 }
 
-// minikubeDockerSystemPrune doese a minikube docker system prune
-func minikubeDockerSystemPrune(profile string) error {
-	args := fmt.Sprintf("./minikube -p %s ssh -- docker system prune -a --volumes -f", profile)
-	c := exec.Command("/bin/bash", "-c", args)
-	if _, err := run(c); err != nil {
-		return fmt.Errorf("failed to minikube docker prune: %v", err)
+// minikubeDockerSystemPrune does a docker system prune on every node of the minikube cluster,
+// only the benchmark images are removed from a reused cluster.
+func minikubeDockerSystemPrune(opts RunOptions) error {
+	nodes, err := minikubeNodes(opts.Profile)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		for _, args := range nodeCacheClearArgs("docker", opts.ReuseCluster) {
+			if _, err := minikubeNodeExec(opts.Profile, node, args...); err != nil {
+				return fmt.Errorf("failed to minikube docker prune: %v", err)
+			}
+		}
 	}
 	return nil
 }
 
 // ClearDockerCache clears out Dockers caching.
-func ClearDockerCache(profile string) error {
-	return DockerSystemPrune()
+func ClearDockerCache(opts RunOptions) error {
+	if err := clearBuilderCache(opts.Builder, buildxHostInstance, ""); err != nil {
+		return err
+	}
+	return PruneDocker()
+}
+
+// ListDockerImages returns the repository and tag of every image in Docker.
+func ListDockerImages() ([]string, error) {
+	return listImages("docker")
 }
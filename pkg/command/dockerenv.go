@@ -3,40 +3,94 @@ package command
 import (
 	"fmt"
 	"os/exec"
-	"strings"
 	"time"
 )
 
 // StartMinikubeDockerEnv starts minikube for docker-env.
-func StartMinikubeDockerEnv(profile string, args ...string) error {
-	return startMinikube(profile, args...)
+func StartMinikubeDockerEnv(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startMinikube(opts, args...)
 }
 
-func StartMinikubeDockerEnvContainerd(profile string, args ...string) error {
-	return startMinikube(profile, "--container-runtime=containerd")
+func StartMinikubeDockerEnvContainerd(opts RunOptions, args ...string) (ClusterStart, error) {
+	arguments := append([]string{"--container-runtime=containerd"}, args...)
+	return startMinikube(opts, arguments...)
 }
 
 // RunDockerEnv builds the provided image using the docker-env method and returns the run time.
-func RunDockerEnv(image string, profile string) (float64, error) {
-	return runDockerEnv(image, profile)
-}
-func RunDockerEnvWithBuildKitDiabled(image string, profile string) (float64, error) {
-	return runDockerEnv(image, profile, "DOCKER_BUILDKIT=0")
-}
-func runDockerEnv(image string, profile string, envs ...string) (float64, error) {
+// If the cluster has multiple nodes, the image is built on every one of them.
+func RunDockerEnv(image string, opts RunOptions) (Timing, error) {
+	targets, err := dockerEnvTargets(opts)
+	if err != nil {
+		return Timing{}, err
+	}
+
 	// build
-	buildArgs := fmt.Sprintf("eval $(./minikube -p %s docker-env) && %s docker build -t benchmark-env -f testdata/Dockerfile.%s .", profile, strings.Join(envs, " "), image)
-	build := exec.Command("/bin/bash", "-c", buildArgs)
 	start := time.Now()
-	if _, err := run(build); err != nil {
-		return 0, fmt.Errorf("failed to build via docker-env: %v", err)
+	for _, t := range targets {
+		buildArgs := t.env + opts.Builder.buildCommand("benchmark-env", image, t.instance)
+		build := exec.Command("/bin/bash", "-c", buildArgs)
+		if _, err := run(build); err != nil {
+			return Timing{}, fmt.Errorf("failed to build via docker-env: %v", err)
+		}
 	}
 	elapsed := time.Now().Sub(start)
 
 	// verify
-	if err := verifyImage("benchmark-env", profile); err != nil {
-		return 0, fmt.Errorf("image was not found after docker-env: %v", err)
+	verify := verifyImage("benchmark-env", opts.Profile)
+	if opts.Nodes > 1 {
+		verify = verifyEveryNode(opts.Profile, "benchmark-env")
+	}
+	if verify != nil {
+		return Timing{}, fmt.Errorf("image was not found after docker-env: %v", verify)
+	}
+
+	return Timing{Total: elapsed.Seconds(), Transfer: elapsed.Seconds()}, nil
+}
+
+// dockerEnvTarget is a Docker daemon inside minikube that images are built on via docker-env.
+type dockerEnvTarget struct {
+	// env is the shell prefix that points Docker at the daemon.
+	env string
+	// instance is the name of the buildx builder instance used on the daemon.
+	instance string
+}
+
+// dockerEnvTargets returns the Docker daemons of every node of the cluster.
+func dockerEnvTargets(opts RunOptions) ([]dockerEnvTarget, error) {
+	if opts.Nodes < 2 {
+		return []dockerEnvTarget{{env: dockerEnv(opts.Profile), instance: buildxDockerEnvInstance}}, nil
+	}
+	nodes, err := minikubeNodes(opts.Profile)
+	if err != nil {
+		return nil, err
 	}
+	targets := []dockerEnvTarget{}
+	for _, node := range nodes {
+		env, err := nodeDockerEnv(opts.Profile, node)
+		if err != nil {
+			return nil, err
+		}
+		// buildx instances are tied to the daemon they were created on, so every node needs its own
+		targets = append(targets, dockerEnvTarget{env: env, instance: buildxDockerEnvInstance + "-" + node})
+	}
+	return targets, nil
+}
 
-	return elapsed.Seconds(), nil
+// dockerEnv returns the shell prefix that points Docker at the daemon inside minikube.
+func dockerEnv(profile string) string {
+	return fmt.Sprintf("eval $(./minikube -p %s docker-env) && ", profile)
+}
+
+// ClearDockerEnvBuilderCache clears out the builder caching related to the docker-env method.
+func ClearDockerEnvBuilderCache(opts RunOptions) error {
+	targets, err := dockerEnvTargets(opts)
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		if err := clearBuilderCache(opts.Builder, t.instance, t.env); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,48 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// CheckDriver returns an error if the provided minikube driver can't be selected or its prerequisites aren't met on the host.
+func CheckDriver(driver string) error {
+	switch driver {
+	case "docker", "podman":
+		if _, err := exec.LookPath(driver); err != nil {
+			return fmt.Errorf("the %s driver requires %s to be installed", driver, driver)
+		}
+		if _, err := run(exec.Command(driver, "version")); err != nil {
+			return fmt.Errorf("the %s driver requires %s to be running: %v", driver, driver, err)
+		}
+	case "kvm2":
+		if _, err := os.Stat("/dev/kvm"); err != nil {
+			return fmt.Errorf("the kvm2 driver requires KVM to be enabled: %v", err)
+		}
+		if _, err := exec.LookPath("virsh"); err != nil {
+			return fmt.Errorf("the kvm2 driver requires libvirt to be installed")
+		}
+	case "qemu":
+		qemu := "qemu-system-x86_64"
+		if runtime.GOARCH == "arm64" {
+			qemu = "qemu-system-aarch64"
+		}
+		if _, err := exec.LookPath(qemu); err != nil {
+			return fmt.Errorf("the qemu driver requires %s to be installed", qemu)
+		}
+	case "none":
+		if os.Geteuid() != 0 {
+			return fmt.Errorf("the none driver requires the benchmark to be run as root")
+		}
+		for _, bin := range []string{"conntrack", "crictl"} {
+			if _, err := exec.LookPath(bin); err != nil {
+				return fmt.Errorf("the none driver requires %s to be installed", bin)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown driver %q", driver)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// hostBuilder is a Docker compatible CLI, other than Docker itself, that is used to build images on the host.
+type hostBuilder struct {
+	// name is the name of the CLI binary.
+	name string
+	// insecurePushFlag is the flag that allows pushing to the registry addon over plain HTTP.
+	insecurePushFlag string
+	// pruneCache clears out the build cache created by the benchmark that isn't removed along with the benchmark's images.
+	pruneCache func() error
+}
+
+// tag returns the tag of the image built by the host builder.
+func (h hostBuilder) tag() string {
+	return "benchmark-" + h.name
+}
+
+// archive returns the archive in the provided dir the image built by the host builder is saved to before being transferred.
+func (h hostBuilder) archive(dir string) string {
+	return filepath.Join(dir, h.tag()+".tar")
+}
+
+// build returns the command that builds the provided image with the provided tag.
+func (h hostBuilder) build(tag string, image string) *exec.Cmd {
+	dockerfile := fmt.Sprintf("testdata/Dockerfile.%s", image)
+	return exec.Command(h.name, "build", "--label", benchmarkLabel, "-t", tag, "-f", dockerfile, ".")
+}
+
+// save saves the provided image to the provided archive.
+func (h hostBuilder) save(tag string, archive string) error {
+	c := exec.Command(h.name, "save", "-o", archive, tag)
+	if _, err := run(c); err != nil {
+		return fmt.Errorf("failed to save image via %s: %v", h.name, err)
+	}
+	return nil
+}
+
+// runRegistry builds the provided image, pushes it to the registry addon and returns the run time.
+func (h hostBuilder) runRegistry(image string, opts RunOptions) (Timing, error) {
+	ip, err := minikubeIP(opts.Profile)
+	if err != nil {
+		return Timing{}, err
+	}
+	repo := h.tag() + "-registry"
+	tag := fmt.Sprintf("%s:5000/%s", ip, repo)
+
+	// build
+	build := h.build(tag, image)
+	start := time.Now()
+	if _, err := run(build); err != nil {
+		return Timing{}, fmt.Errorf("failed to build via %s: %v", h.name, err)
+	}
+
+	// push
+	push := exec.Command(h.name, "push", h.insecurePushFlag, tag)
+	transferStart := time.Now()
+	if _, err := run(push); err != nil {
+		return Timing{}, fmt.Errorf("failed to push via %s: %v", h.name, err)
+	}
+	elapsed := time.Now().Sub(start)
+	transfer := time.Now().Sub(transferStart)
+
+	// verify
+	verifyArgs := fmt.Sprintf("curl http://%s:5000/v2/_catalog | grep %s", ip, repo)
+	verify := exec.Command("/bin/bash", "-c", verifyArgs)
+	o, err := run(verify)
+	if err != nil {
+		return Timing{}, fmt.Errorf("failed to check if image was pushed successfully: %v", err)
+	}
+	if string(o) == "" {
+		return Timing{}, fmt.Errorf("image was not successfully pushed")
+	}
+
+	return Timing{Total: elapsed.Seconds(), Transfer: transfer.Seconds()}, nil
+}
+
+// runArchive builds the provided image, saves it to an archive, loads it into the provided cluster from the archive and returns the run time.
+func (h hostBuilder) runArchive(image string, p ClusterProvider) (Timing, error) {
+	dir, err := archiveDir()
+	if err != nil {
+		return Timing{}, err
+	}
+	defer os.RemoveAll(dir)
+	archive := h.archive(dir)
+
+	// build
+	build := h.build(h.tag(), image)
+	start := time.Now()
+	if _, err := run(build); err != nil {
+		return Timing{}, fmt.Errorf("failed to build via %s: %v", h.name, err)
+	}
+
+	// save
+	saveStart := time.Now()
+	if err := h.save(h.tag(), archive); err != nil {
+		return Timing{}, err
+	}
+	save := time.Now().Sub(saveStart)
+
+	// load
+	transferStart := time.Now()
+	if err := p.LoadImage(archive); err != nil {
+		return Timing{}, err
+	}
+	elapsed := time.Now().Sub(start)
+	transfer := time.Now().Sub(transferStart)
+
+	// verify
+	if err := verifyClusterImage(p, h.tag()); err != nil {
+		return Timing{}, fmt.Errorf("image was not found after %s load: %v", p.Name(), err)
+	}
+
+	return Timing{Total: elapsed.Seconds(), Transfer: transfer.Seconds(), Save: save.Seconds()}, nil
+}
+
+// prune removes the images created by the benchmark along with the build cache.
+func (h hostBuilder) prune() error {
+	if err := pruneImages(h.name); err != nil {
+		return err
+	}
+	return h.pruneCache()
+}
+
+// startMinikubeRegistryAddon starts minikube with the provided runtime and enables the registry addon.
+// Unlike startMinikubeRegistry the host isn't reconfigured, as the host builders can push to an insecure registry directly.
+func startMinikubeRegistryAddon(opts RunOptions, runtime string, otherStartArgs ...string) (ClusterStart, error) {
+	runtime = fmt.Sprintf("--container-runtime=%s", runtime)
+	arguments := append([]string{runtime}, otherStartArgs...)
+	s, err := startMinikube(opts, arguments...)
+	if err != nil {
+		return s, err
+	}
+
+	return s, enableRegistryAddon(opts.Profile)
+}
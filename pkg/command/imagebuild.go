@@ -7,31 +7,111 @@ import (
 )
 
 // StartMinikubeImageBuildDocker starts minikube for docker image build.
-func StartMinikubeImageBuildDocker(profile string, args ...string) error {
-	return startMinikube(profile, args...)
+func StartMinikubeImageBuildDocker(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startMinikubeImageBuild(opts, args...)
 }
 
 // StartMinikubeImageBuildContainerd starts minikube for containerd image build.
-func StartMinikubeImageBuildContainerd(profile string, args ...string) error {
+func StartMinikubeImageBuildContainerd(opts RunOptions, args ...string) (ClusterStart, error) {
 	arguments := append([]string{"--container-runtime=containerd"}, args...)
-	return startMinikube(profile, arguments...)
+	return startMinikubeImageBuild(opts, arguments...)
 }
 
 // StartMinikubeImageBuildCrio start minikube for crio image build.
-func StartMinikubeImageBuildCrio(profile string, args ...string) error {
+func StartMinikubeImageBuildCrio(opts RunOptions, args ...string) (ClusterStart, error) {
 	arguments := append([]string{"--container-runtime=cri-o"}, args...)
-	return startMinikube(profile, arguments...)
+	return startMinikubeImageBuild(opts, arguments...)
+}
+
+// startMinikubeImageBuild starts minikube, enabling the registry addon if the built image is pushed to it.
+func startMinikubeImageBuild(opts RunOptions, args ...string) (ClusterStart, error) {
+	s, err := startMinikube(opts, args...)
+	if err != nil || !opts.ImageBuild.Push {
+		return s, err
+	}
+
+	return s, enableRegistryAddon(opts.Profile)
+}
+
+// ImageBuildOptions contains the options passed to minikube image build.
+type ImageBuildOptions struct {
+	// BuildOpts are passed to the builder via --build-opt.
+	BuildOpts []string
+	// BuildEnvs are set in the builder's environment via --build-env.
+	BuildEnvs []string
+	// Push pushes the built image to the registry addon.
+	Push bool
+	// BuildkitHost is the address of the buildkitd used to build on containerd, it's passed as the BUILDKIT_HOST build env.
+	BuildkitHost string
 }
 
 // RunImageBuild builds the provided image using the image build method and returns the run time.
-func RunImageBuild(image string, profile string) (float64, error) {
+func RunImageBuild(image string, opts RunOptions) (Timing, error) {
+	return runImageBuild(image, opts, opts.ImageBuild.BuildEnvs)
+}
+
+// RunImageBuildContainerd builds the provided image using the image build method on containerd and returns the run time.
+func RunImageBuildContainerd(image string, opts RunOptions) (Timing, error) {
+	envs := opts.ImageBuild.BuildEnvs
+	if opts.ImageBuild.BuildkitHost != "" {
+		envs = append([]string{"BUILDKIT_HOST=" + opts.ImageBuild.BuildkitHost}, envs...)
+	}
+	return runImageBuild(image, opts, envs)
+}
+
+func runImageBuild(image string, opts RunOptions, envs []string) (Timing, error) {
+	tag := "benchmark-image-build"
+	if opts.ImageBuild.Push {
+		// the registry addon is exposed on port 5000 of every node
+		tag = "localhost:5000/benchmark-image-build"
+	}
+
 	dockerfile := fmt.Sprintf("testdata/Dockerfile.%s", image)
-	imageBuild := exec.Command("./minikube", "-p", profile, "image", "build", "-t", "benchmark-image-build", "-f", dockerfile, ".")
+	args := []string{"-p", opts.Profile, "image", "build", "-t", tag, "-f", dockerfile}
+	for _, o := range opts.ImageBuild.BuildOpts {
+		args = append(args, "--build-opt="+o)
+	}
+	for _, e := range envs {
+		args = append(args, "--build-env="+e)
+	}
+	if opts.ImageBuild.Push {
+		args = append(args, "--push")
+	}
+	if opts.Nodes > 1 {
+		args = append(args, "--all")
+	}
+	args = append(args, ".")
+	imageBuild := exec.Command("./minikube", args...)
 	start := time.Now()
 	if _, err := run(imageBuild); err != nil {
-		return 0, fmt.Errorf("failed to image build: %v", err)
+		return Timing{}, fmt.Errorf("failed to image build: %v", err)
 	}
 	elapsed := time.Now().Sub(start)
 
-	return elapsed.Seconds(), nil
+	// verify
+	if opts.Nodes > 1 {
+		if err := verifyEveryNode(opts.Profile, "benchmark-image-build"); err != nil {
+			return Timing{}, fmt.Errorf("image was not found after image build: %v", err)
+		}
+	}
+
+	return Timing{Total: elapsed.Seconds(), Transfer: elapsed.Seconds()}, nil
+}
+
+// ClearImageBuildDockerCache clears out caching related to the docker image build method,
+// emptying the registry addon as well if the built image is pushed to it.
+func ClearImageBuildDockerCache(opts RunOptions) error {
+	if opts.ImageBuild.Push {
+		return resetRegistryAddon(opts, ClearDockerAndMinikubeDockerCache)
+	}
+	return ClearDockerAndMinikubeDockerCache(opts)
+}
+
+// ClearImageBuildCache clears out caching related to the containerd and crio image build methods,
+// emptying the registry addon as well if the built image is pushed to it.
+func ClearImageBuildCache(opts RunOptions) error {
+	if opts.ImageBuild.Push {
+		return resetRegistryAddon(opts, ClearDockerAndMinikubeNodeCache)
+	}
+	return ClearDockerAndMinikubeNodeCache(opts)
 }
@@ -2,47 +2,104 @@ package command
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
 	"time"
 )
 
 // StartMinikubeImageLoadDocker starts minikube for docker image load.
-func StartMinikubeImageLoadDocker(profile string, args ...string) error {
-	return startMinikube(profile, args...)
+func StartMinikubeImageLoadDocker(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startMinikube(opts, args...)
 }
 
 // StartMinikubeImageLoadContainerd starts minikube for containerd image load.
-func StartMinikubeImageLoadContainerd(profile string, args ...string) error {
-	return startMinikube(profile, "--container-runtime=containerd")
+func StartMinikubeImageLoadContainerd(opts RunOptions, args ...string) (ClusterStart, error) {
+	arguments := append([]string{"--container-runtime=containerd"}, args...)
+	return startMinikube(opts, arguments...)
 }
 
 // StartMinikubeImageLoadCrio start minikube for crio image load.
-func StartMinikubeImageLoadCrio(profile string, args ...string) error {
+func StartMinikubeImageLoadCrio(opts RunOptions, args ...string) (ClusterStart, error) {
 	arguments := append([]string{"--container-runtime=cri-o"}, args...)
-	return startMinikube(profile, arguments...)
+	return startMinikube(opts, arguments...)
 }
 
-// RunImageLoad builds the provided image using the image load method and returns the run time.
-func RunImageLoad(image string, profile string) (float64, error) {
+// StartMinikubeImageLoadRemoteDocker starts minikube for docker image load from a remote registry.
+func StartMinikubeImageLoadRemoteDocker(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startMinikubeWithLocalRegistry(opts, "docker", args...)
+}
+
+// StartMinikubeImageLoadRemoteContainerd starts minikube for containerd image load from a remote registry.
+func StartMinikubeImageLoadRemoteContainerd(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startMinikubeWithLocalRegistry(opts, "containerd", args...)
+}
+
+// StartMinikubeImageLoadRemoteCrio starts minikube for crio image load from a remote registry.
+func StartMinikubeImageLoadRemoteCrio(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startMinikubeWithLocalRegistry(opts, "cri-o", args...)
+}
+
+// RunImageLoad builds the provided image, loads it via image load from the Docker daemon and returns the run time.
+// minikube image load loads the image into every node, which is verified if there are multiple.
+func RunImageLoad(image string, opts RunOptions) (Timing, error) {
+	t, err := runLoadImage(NewMinikubeProvider(opts.Profile), "benchmark-image", image, opts)
+	if err != nil || opts.Nodes < 2 {
+		return t, err
+	}
+
+	// verify
+	if err := verifyEveryNode(opts.Profile, "benchmark-image"); err != nil {
+		return Timing{}, fmt.Errorf("image was not found after image load: %v", err)
+	}
+
+	return t, nil
+}
+
+// RunImageLoadArchive builds the provided image, saves it to an archive, loads it via image load from the archive and returns the run time.
+func RunImageLoadArchive(image string, opts RunOptions) (Timing, error) {
+	return runLoadArchive(NewMinikubeProvider(opts.Profile), "benchmark-image-archive", image, opts)
+}
+
+// RunImageLoadRemote builds the provided image, pushes it to the local registry, loads it via image load from the registry and returns the run time.
+func RunImageLoadRemote(image string, opts RunOptions) (Timing, error) {
+	tag := localRegistry + "/benchmark-image-remote"
+
 	// build
-	dockerfile := fmt.Sprintf("testdata/Dockerfile.%s", image)
-	build := exec.Command("docker", "build", "-t", "benchmark-image", "-f", dockerfile, ".")
+	build := dockerBuild(opts.Builder, tag, image)
 	start := time.Now()
 	if _, err := run(build); err != nil {
-		return 0, fmt.Errorf("failed to build via image load: %v", err)
+		return Timing{}, fmt.Errorf("failed to build via image load: %v", err)
+	}
+
+	// push
+	push := exec.Command("docker", "push", tag)
+	transferStart := time.Now()
+	if _, err := run(push); err != nil {
+		return Timing{}, fmt.Errorf("failed to push to local registry: %v", err)
 	}
 
 	// image load
-	imageLoad := exec.Command("./minikube", "-p", profile, "image", "load", "benchmark-image:latest")
+	imageLoad := exec.Command("./minikube", "-p", opts.Profile, "image", "load", "--daemon=false", "--remote=true", tag)
 	if _, err := run(imageLoad); err != nil {
-		return 0, fmt.Errorf("failed to image load: %v", err)
+		return Timing{}, fmt.Errorf("failed to image load: %v", err)
 	}
 	elapsed := time.Now().Sub(start)
+	transfer := time.Now().Sub(transferStart)
 
 	// verify
-	if err := verifyImage("benchmark-image", profile); err != nil {
-		return 0, fmt.Errorf("image was not found after image load: %v", err)
+	if err := verifyImage("benchmark-image-remote", opts.Profile); err != nil {
+		return Timing{}, fmt.Errorf("image was not found after image load: %v", err)
 	}
 
-	return elapsed.Seconds(), nil
+	return Timing{Total: elapsed.Seconds(), Transfer: transfer.Seconds()}, nil
+}
+
+// archiveDir creates a temporary dir to save an image archive to, outside the build context so the archive isn't sent
+// along with every later build.
+func archiveDir() (string, error) {
+	dir, err := os.MkdirTemp("", "benchmark-archive")
+	if err != nil {
+		return "", fmt.Errorf("failed to create image archive dir: %v", err)
+	}
+	return dir, nil
 }
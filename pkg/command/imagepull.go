@@ -0,0 +1,51 @@
+package command
+
+import (
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// StartMinikubeImagePullDocker starts minikube for docker image pull.
+func StartMinikubeImagePullDocker(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startMinikubeImagePull(opts, "docker", args...)
+}
+
+// StartMinikubeImagePullContainerd starts minikube for containerd image pull.
+func StartMinikubeImagePullContainerd(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startMinikubeImagePull(opts, "containerd", args...)
+}
+
+// StartMinikubeImagePullCrio starts minikube for crio image pull.
+func StartMinikubeImagePullCrio(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startMinikubeImagePull(opts, "cri-o", args...)
+}
+
+// startMinikubeImagePull starts minikube with the local registry marked as insecure, so the runtime can pull from it.
+func startMinikubeImagePull(opts RunOptions, runtime string, otherStartArgs ...string) (ClusterStart, error) {
+	arguments := append([]string{"--insecure-registry=" + localRegistryFromMinikube}, otherStartArgs...)
+	return startMinikubeWithLocalRegistry(opts, runtime, arguments...)
+}
+
+// RunImagePull builds the provided image, pushes it to the local registry, pulls it from inside minikube and returns the run time.
+func RunImagePull(image string, opts RunOptions) (Timing, error) {
+	build, push, _, err := pushToLocalRegistry(image, "benchmark-image-pull", opts)
+	if err != nil {
+		return Timing{}, fmt.Errorf("failed to prepare image pull: %v", err)
+	}
+
+	// image pull
+	imagePull := exec.Command("./minikube", "-p", opts.Profile, "image", "pull", localRegistryFromMinikube+"/benchmark-image-pull")
+	start := time.Now()
+	if _, err := run(imagePull); err != nil {
+		return Timing{}, fmt.Errorf("failed to image pull: %v", err)
+	}
+	transfer := push + time.Now().Sub(start)
+
+	// verify
+	if err := verifyImage("benchmark-image-pull", opts.Profile); err != nil {
+		return Timing{}, fmt.Errorf("image was not found after image pull: %v", err)
+	}
+
+	return Timing{Total: (build + transfer).Seconds(), Transfer: transfer.Seconds()}, nil
+}
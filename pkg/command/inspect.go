@@ -0,0 +1,49 @@
+package command
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ImageInfo contains the metadata of a built benchmark image.
+type ImageInfo struct {
+	// ID is the local image ID, the digest of the image config, as the image is never pushed it has no repo digest.
+	ID     string
+	Size   int64
+	Layers int
+}
+
+// InspectImage builds the provided image and returns its size, layer count and image ID.
+func InspectImage(image string) (ImageInfo, error) {
+	build := dockerBuild(BuilderDefault, "benchmark-inspect", image)
+	if _, err := run(build); err != nil {
+		return ImageInfo{}, fmt.Errorf("failed to build image for inspection: %v", err)
+	}
+
+	inspect := exec.Command("docker", "image", "inspect", "--format", "{{.Id}} {{.Size}} {{len .RootFS.Layers}}", "benchmark-inspect")
+	o, err := run(inspect)
+	if err != nil {
+		return ImageInfo{}, fmt.Errorf("failed to inspect image: %v", err)
+	}
+
+	fields := strings.Fields(o)
+	if len(fields) != 3 {
+		return ImageInfo{}, fmt.Errorf("unexpected image inspect output: %q", o)
+	}
+	size, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return ImageInfo{}, fmt.Errorf("failed to parse image size: %v", err)
+	}
+	layers, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return ImageInfo{}, fmt.Errorf("failed to parse image layer count: %v", err)
+	}
+
+	return ImageInfo{
+		ID:     fields[0],
+		Size:   size,
+		Layers: layers,
+	}, nil
+}
@@ -1,48 +1,138 @@
 package command
 
 import (
+	"encoding/json"
 	"fmt"
 	"os/exec"
-	"time"
 )
 
-func StartK3d(profile string, args ...string) error {
-	c := exec.Command("k3d", "cluster", "create", "benchmark")
+// k3dProvider is the ClusterProvider of k3d.
+type k3dProvider struct {
+	name string
+}
+
+// NewK3dProvider returns the ClusterProvider of the k3d cluster with the provided name.
+func NewK3dProvider(name string) ClusterProvider {
+	return k3dProvider{name: name}
+}
+
+func (k k3dProvider) Name() string {
+	return ProviderK3d
+}
+
+func (k k3dProvider) Start(args ...string) (ClusterStart, error) {
+	a := append([]string{"cluster", "create", k.name}, args...)
+	c := exec.Command("k3d", a...)
+	if _, err := run(c); err != nil {
+		return ClusterStart{}, fmt.Errorf("failed to start k3d: %v", err)
+	}
+
+	return ClusterStart{Command: c.String()}, nil
+}
+
+func (k k3dProvider) Delete() error {
+	exists, err := k.Exists()
+	if err != nil || !exists {
+		return err
+	}
+	c := exec.Command("k3d", "cluster", "delete", k.name)
 	if _, err := run(c); err != nil {
-		return fmt.Errorf("failed to start k3d: %v", err)
+		return fmt.Errorf("failed to delete k3d: %v", err)
 	}
 
 	return nil
 }
 
-func RunK3d(image string, profile string) (float64, error) {
-	// build
-	dockerfile := fmt.Sprintf("testdata/Dockerfile.%s", image)
-	build := exec.Command("docker", "build", "-t", "benchmark-k3d", "-f", dockerfile, ".")
-	start := time.Now()
-	if _, err := run(build); err != nil {
-		return 0, fmt.Errorf("failed to build via k3d: %v", err)
+func (k k3dProvider) Exists() (bool, error) {
+	clusters, err := ListK3dClusters()
+	if err != nil {
+		return false, err
 	}
+	return contains(clusters, k.name), nil
+}
 
-	// kind load
-	imageLoad := exec.Command("k3d", "image", "import", "-c", "benchmark", "benchmark-k3d:latest")
-	if _, err := run(imageLoad); err != nil {
-		return 0, fmt.Errorf("failed to k3d load: %v", err)
+func (k k3dProvider) LoadImage(image string) error {
+	c := exec.Command("k3d", "image", "import", "-c", k.name, image)
+	if _, err := run(c); err != nil {
+		return fmt.Errorf("failed to k3d load: %v", err)
 	}
-	elapsed := time.Now().Sub(start)
+	return nil
+}
 
-	return elapsed.Seconds(), nil
+func (k k3dProvider) WaitReady() error {
+	return waitReady("kubectl", "--context", "k3d-"+k.name)
 }
 
-func ClearK3dCache(profile string) error {
-	return DockerSystemPrune()
+func (k k3dProvider) Runtime() (string, error) {
+	return crictlRuntime(k)
 }
 
-func deleteK3d() error {
-	c := exec.Command("k3d", "cluster", "delete")
-	if _, err := run(c); err != nil {
-		return fmt.Errorf("failed to delete k3d: %v", err)
+func (k k3dProvider) ListImages() ([]string, error) {
+	return crictlImages(k)
+}
+
+func (k k3dProvider) NodeExec(args ...string) (string, error) {
+	a := append([]string{"exec", k.node()}, args...)
+	o, err := output(exec.Command("docker", a...))
+	if err != nil {
+		return "", fmt.Errorf("failed to run command on %s: %v", k.node(), err)
 	}
+	return o, nil
+}
 
-	return nil
+// node returns the name of the container running the server node.
+func (k k3dProvider) node() string {
+	return "k3d-" + k.name + "-server-0"
+}
+
+// k3dNetwork returns the name of the Docker network the nodes of the k3d cluster with the provided name are on.
+func k3dNetwork(name string) string {
+	return "k3d-" + name
+}
+
+func StartK3d(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startCluster(NewK3dProvider(opts.Profile), "containerd", opts, args...)
+}
+
+func RunK3d(image string, opts RunOptions) (Timing, error) {
+	return runLoadImage(NewK3dProvider(opts.Profile), "benchmark-k3d", image, opts)
+}
+
+// ClearK3dCache clears out Dockers caching on the host along with the image store and build cache of the k3d node.
+func ClearK3dCache(opts RunOptions) error {
+	if err := ClearDockerCache(opts); err != nil {
+		return err
+	}
+	return clearClusterNodeCache(NewK3dProvider(opts.Profile), opts)
+}
+
+// DeleteK3d deletes the k3d cluster along with the local registry the pull-through method may have started.
+func DeleteK3d(opts RunOptions) error {
+	if err := deleteCluster(NewK3dProvider(opts.Profile), opts); err != nil {
+		return err
+	}
+
+	return deleteLocalRegistry()
+}
+
+// ListK3dClusters returns the names of all the existing k3d clusters.
+func ListK3dClusters() ([]string, error) {
+	if _, err := exec.LookPath("k3d"); err != nil {
+		return nil, nil
+	}
+	o, err := output(exec.Command("k3d", "cluster", "list", "-o", "json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list k3d clusters: %v", err)
+	}
+	var clusters []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal([]byte(o), &clusters); err != nil {
+		return nil, fmt.Errorf("failed to parse k3d clusters: %v", err)
+	}
+	names := []string{}
+	for _, c := range clusters {
+		names = append(names, c.Name)
+	}
+	return names, nil
 }
@@ -2,47 +2,126 @@ package command
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
-	"time"
 )
 
-func StartKind(profile string, args ...string) error {
-	c := exec.Command("./kind", "create", "cluster")
+// kindProvider is the ClusterProvider of kind.
+type kindProvider struct {
+	name string
+}
+
+// NewKindProvider returns the ClusterProvider of the kind cluster with the provided name.
+func NewKindProvider(name string) ClusterProvider {
+	return kindProvider{name: name}
+}
+
+func (k kindProvider) Name() string {
+	return ProviderKind
+}
+
+func (k kindProvider) Start(args ...string) (ClusterStart, error) {
+	a := append([]string{"create", "cluster", "--name", k.name}, args...)
+	c := exec.Command("./kind", a...)
+	if _, err := run(c); err != nil {
+		return ClusterStart{}, fmt.Errorf("failed to start kind: %v", err)
+	}
+
+	return ClusterStart{Command: c.String()}, nil
+}
+
+func (k kindProvider) Delete() error {
+	exists, err := k.Exists()
+	if err != nil || !exists {
+		return err
+	}
+	c := exec.Command("./kind", "delete", "cluster", "--name", k.name)
 	if _, err := run(c); err != nil {
-		return fmt.Errorf("failed to start kind: %v", err)
+		return fmt.Errorf("failed to delete kind: %v", err)
 	}
 
 	return nil
 }
 
-func RunKind(image string, profile string) (float64, error) {
-	// build
-	dockerfile := fmt.Sprintf("testdata/Dockerfile.%s", image)
-	build := exec.Command("docker", "build", "-t", "benchmark-kind", "-f", dockerfile, ".")
-	start := time.Now()
-	if _, err := run(build); err != nil {
-		return 0, fmt.Errorf("failed to build via kind: %v", err)
+func (k kindProvider) Exists() (bool, error) {
+	clusters, err := ListKindClusters()
+	if err != nil {
+		return false, err
 	}
+	return contains(clusters, k.name), nil
+}
 
-	// kind load
-	imageLoad := exec.Command("./kind", "load", "docker-image", "benchmark-kind:latest")
-	if _, err := run(imageLoad); err != nil {
-		return 0, fmt.Errorf("failed to kind load: %v", err)
+func (k kindProvider) LoadImage(image string) error {
+	source := "docker-image"
+	if isArchive(image) {
+		source = "image-archive"
+	}
+	c := exec.Command("./kind", "load", source, "--name", k.name, image)
+	if _, err := run(c); err != nil {
+		return fmt.Errorf("failed to kind load: %v", err)
 	}
-	elapsed := time.Now().Sub(start)
+	return nil
+}
 
-	return elapsed.Seconds(), nil
+func (k kindProvider) WaitReady() error {
+	return waitReady("kubectl", "--context", "kind-"+k.name)
 }
 
-func ClearKindCache(profile string) error {
-	return DockerSystemPrune()
+func (k kindProvider) Runtime() (string, error) {
+	return crictlRuntime(k)
 }
 
-func deleteKind() error {
-	c := exec.Command("./kind", "delete", "cluster")
-	if _, err := run(c); err != nil {
-		return fmt.Errorf("failed to delete kind: %v", err)
+func (k kindProvider) ListImages() ([]string, error) {
+	return crictlImages(k)
+}
+
+func (k kindProvider) NodeExec(args ...string) (string, error) {
+	a := append([]string{"exec", k.node()}, args...)
+	o, err := output(exec.Command("docker", a...))
+	if err != nil {
+		return "", fmt.Errorf("failed to run command on %s: %v", k.node(), err)
 	}
+	return o, nil
+}
 
-	return nil
+// node returns the name of the container running the control plane node.
+func (k kindProvider) node() string {
+	return k.name + "-control-plane"
+}
+
+func StartKind(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startCluster(NewKindProvider(opts.Profile), "containerd", opts, args...)
+}
+
+func RunKind(image string, opts RunOptions) (Timing, error) {
+	return runLoadImage(NewKindProvider(opts.Profile), "benchmark-kind", image, opts)
+}
+
+// ClearKindCache clears out Dockers caching on the host along with the image store and build cache of the kind node.
+func ClearKindCache(opts RunOptions) error {
+	if err := ClearDockerCache(opts); err != nil {
+		return err
+	}
+	return clearClusterNodeCache(NewKindProvider(opts.Profile), opts)
+}
+
+// DeleteKind deletes the kind cluster along with the local registry the pull-through method may have started.
+func DeleteKind(opts RunOptions) error {
+	if err := deleteCluster(NewKindProvider(opts.Profile), opts); err != nil {
+		return err
+	}
+
+	return deleteLocalRegistry()
+}
+
+// ListKindClusters returns the names of all the existing kind clusters.
+func ListKindClusters() ([]string, error) {
+	if _, err := os.Stat("./kind"); os.IsNotExist(err) {
+		return nil, nil
+	}
+	o, err := output(exec.Command("./kind", "get", "clusters"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list kind clusters: %v", err)
+	}
+	return lines(o), nil
 }
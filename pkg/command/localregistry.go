@@ -0,0 +1,109 @@
+package command
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// localRegistryName is the name of the registry container run on the host.
+	localRegistryName = "benchmark-local-registry"
+	// localRegistry is the address the local registry is reachable at from the host.
+	// localhost registries are treated as insecure by both Docker and minikube, so no configuration is required.
+	localRegistry = "localhost:5001"
+	// localRegistryFromMinikube is the address the local registry is reachable at from inside minikube.
+	localRegistryFromMinikube = "host.minikube.internal:5001"
+)
+
+// startMinikubeWithLocalRegistry starts minikube with the provided runtime and runs the local registry.
+func startMinikubeWithLocalRegistry(opts RunOptions, runtime string, otherStartArgs ...string) (ClusterStart, error) {
+	runtime = fmt.Sprintf("--container-runtime=%s", runtime)
+	arguments := append([]string{runtime}, otherStartArgs...)
+	s, err := startMinikube(opts, arguments...)
+	if err != nil {
+		return s, err
+	}
+
+	return s, startLocalRegistry()
+}
+
+// startLocalRegistry runs a registry container on the host, standing in for a remote registry.
+func startLocalRegistry() error {
+	a := append([]string{"run", "-d", "-p", "5001:5000", "--name", localRegistryName}, sandboxRunArgs()...)
+	c := exec.Command("docker", append(a, "registry:2")...)
+	if _, err := run(c); err != nil {
+		return fmt.Errorf("failed to start local registry: %v", err)
+	}
+
+	return nil
+}
+
+// deleteLocalRegistry removes the local registry container along with the images pushed to it, if it exists.
+func deleteLocalRegistry() error {
+	args := fmt.Sprintf("if docker container inspect %[1]s > /dev/null 2>&1; then docker rm -f -v %[1]s; fi", localRegistryName)
+	c := exec.Command("/bin/bash", "-c", args)
+	if _, err := run(c); err != nil {
+		return fmt.Errorf("failed to delete local registry: %v", err)
+	}
+
+	return nil
+}
+
+// pushToLocalRegistry builds the provided image with the provided repository name, pushes it to the local registry
+// and then removes it from the Docker daemon, so it can only be retrieved from the registry.
+// It returns the time spent building, the time spent pushing and the ID of the pushed image.
+func pushToLocalRegistry(image string, repo string, opts RunOptions) (time.Duration, time.Duration, string, error) {
+	tag := localRegistry + "/" + repo
+
+	// build
+	build := dockerBuild(opts.Builder, tag, image)
+	buildStart := time.Now()
+	if _, err := run(build); err != nil {
+		return 0, 0, "", fmt.Errorf("failed to build image: %v", err)
+	}
+	built := time.Now().Sub(buildStart)
+
+	// push
+	push := exec.Command("docker", "push", tag)
+	pushStart := time.Now()
+	if _, err := run(push); err != nil {
+		return 0, 0, "", fmt.Errorf("failed to push to local registry: %v", err)
+	}
+	pushed := time.Now().Sub(pushStart)
+
+	id, err := run(exec.Command("docker", "image", "inspect", "--format", "{{.Id}}", tag))
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("failed to inspect pushed image: %v", err)
+	}
+
+	// untag, so the image isn't retrieved from the Docker daemon
+	rmi := exec.Command("docker", "rmi", tag)
+	if _, err := run(rmi); err != nil {
+		return 0, 0, "", fmt.Errorf("failed to remove image from Docker: %v", err)
+	}
+
+	return built, pushed, strings.TrimSpace(id), nil
+}
+
+// resetLocalRegistry empties the local registry, clearing out any other caching with the provided cache clear in between.
+func resetLocalRegistry(opts RunOptions, cacheClear func(opts RunOptions) error) error {
+	if err := deleteLocalRegistry(); err != nil {
+		return err
+	}
+	if err := cacheClear(opts); err != nil {
+		return err
+	}
+	return startLocalRegistry()
+}
+
+// ClearLocalRegistryAndDockerCache clears out the local registry along with Dockers caching.
+func ClearLocalRegistryAndDockerCache(opts RunOptions) error {
+	return resetLocalRegistry(opts, ClearDockerCache)
+}
+
+// ClearLocalRegistryAndDockerAndMinikubeDockerCache clears out the local registry along with Dockers caching on the host and in minikube.
+func ClearLocalRegistryAndDockerAndMinikubeDockerCache(opts RunOptions) error {
+	return resetLocalRegistry(opts, ClearDockerAndMinikubeDockerCache)
+}
@@ -3,53 +3,130 @@ package command
 import (
 	"fmt"
 	"os/exec"
-	"time"
+	"strings"
 )
 
-func StartMicrok8s(profile string, args ...string) error {
+// microk8sProvider is the ClusterProvider of microk8s, which runs directly on the host as its only cluster.
+type microk8sProvider struct{}
+
+// NewMicrok8sProvider returns the ClusterProvider of microk8s.
+func NewMicrok8sProvider() ClusterProvider {
+	return microk8sProvider{}
+}
+
+func (m microk8sProvider) Name() string {
+	return ProviderMicrok8s
+}
+
+// Start starts microk8s, the args are ignored as microk8s is configured when it's installed.
+func (m microk8sProvider) Start(args ...string) (ClusterStart, error) {
 	c := exec.Command("microk8s", "start")
 	if _, err := run(c); err != nil {
-		return fmt.Errorf("failed to start microk8s: %v", err)
+		return ClusterStart{}, fmt.Errorf("failed to start microk8s: %v", err)
+	}
+
+	return ClusterStart{Command: c.String()}, nil
+}
+
+// Delete stops microk8s, as the cluster is installed rather than created by the benchmark.
+func (m microk8sProvider) Delete() error {
+	exists, err := m.Exists()
+	if err != nil || !exists {
+		return err
+	}
+	c := exec.Command("microk8s", "stop")
+	if _, err := run(c); err != nil {
+		return fmt.Errorf("failed to stop microk8s: %v", err)
 	}
 
 	return nil
 }
 
-func RunMicrok8s(image string, profile string) (float64, error) {
-	// build
-	dockerfile := fmt.Sprintf("testdata/Dockerfile.%s", image)
-	build := exec.Command("docker", "build", "-t", "benchmark-microk8s", "-f", dockerfile, ".")
-	start := time.Now()
-	if _, err := run(build); err != nil {
-		return 0, fmt.Errorf("failed to build via microk8s: %v", err)
+// Exists returns whether microk8s is installed and running.
+func (m microk8sProvider) Exists() (bool, error) {
+	if _, err := exec.LookPath("microk8s"); err != nil {
+		return false, nil
+	}
+	o, err := output(exec.Command("microk8s", "status"))
+	if err != nil {
+		return false, fmt.Errorf("failed to get microk8s status: %v", err)
 	}
+	return !strings.Contains(o, "not running"), nil
+}
 
-	// save
-	args := "docker save benchmark-microk8s > benchmark-microk8s.tar"
-	push := exec.Command("/bin/bash", "-c", args)
-	if _, err := run(push); err != nil {
-		return 0, fmt.Errorf("failed to save image via microk8s: %v", err)
+func (m microk8sProvider) LoadImage(image string) error {
+	c := exec.Command("microk8s", "ctr", "image", "import", image)
+	if !isArchive(image) {
+		args := fmt.Sprintf("docker save %s | microk8s ctr image import -", image)
+		c = exec.Command("/bin/bash", "-c", args)
+	}
+	if _, err := run(c); err != nil {
+		return fmt.Errorf("failed to microk8s load: %v", err)
 	}
+	return nil
+}
+
+func (m microk8sProvider) WaitReady() error {
+	return waitReady("microk8s", "kubectl")
+}
+
+// Runtime returns containerd, as it's the only runtime microk8s runs.
+func (m microk8sProvider) Runtime() (string, error) {
+	return "containerd", nil
+}
 
-	// microk8s load
-	imageLoad := exec.Command("microk8s", "ctr", "image", "import", "benchmark-microk8s.tar")
-	if _, err := run(imageLoad); err != nil {
-		return 0, fmt.Errorf("failed to microk8s load: %v", err)
+func (m microk8sProvider) ListImages() ([]string, error) {
+	o, err := output(exec.Command("microk8s", "ctr", "image", "ls", "-q"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list microk8s images: %v", err)
 	}
-	elapsed := time.Now().Sub(start)
+	return lines(o), nil
+}
 
-	return elapsed.Seconds(), nil
+// NodeExec runs the provided command as root on the host, as that's where microk8s runs.
+func (m microk8sProvider) NodeExec(args ...string) (string, error) {
+	o, err := output(exec.Command("sudo", args...))
+	if err != nil {
+		return "", fmt.Errorf("failed to run command on microk8s: %v", err)
+	}
+	return o, nil
 }
 
-func ClearMicrok8sCache(profile string) error {
-	return DockerSystemPrune()
+func StartMicrok8s(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startCluster(NewMicrok8sProvider(), "containerd", opts, args...)
 }
 
-func deleteMicrok8s() error {
-	c := exec.Command("microk8s", "stop")
+func RunMicrok8s(image string, opts RunOptions) (Timing, error) {
+	return runLoadArchive(NewMicrok8sProvider(), "benchmark-microk8s", image, opts)
+}
+
+// ClearMicrok8sCache clears out Dockers caching on the host along with the benchmark images in microk8s, then verifies that none is left.
+// The other images and the build cache are left, as microk8s is installed rather than created by the benchmark.
+func ClearMicrok8sCache(opts RunOptions) error {
+	if err := ClearDockerCache(opts); err != nil {
+		return err
+	}
+
+	// microk8s runs its own containerd, which is only reachable through microk8s ctr
+	c := exec.Command("/bin/bash", "-c", "microk8s ctr image ls -q | grep benchmark | xargs -r microk8s ctr image rm")
 	if _, err := run(c); err != nil {
-		return fmt.Errorf("failed to stop microk8s: %v", err)
+		return fmt.Errorf("failed to clear microk8s cache: %v", err)
 	}
 
+	// verify
+	images, err := NewMicrok8sProvider().ListImages()
+	if err != nil {
+		return err
+	}
+	for _, i := range images {
+		if strings.Contains(i, "benchmark") {
+			return fmt.Errorf("microk8s still has a benchmark image after clearing its cache: %s", i)
+		}
+	}
 	return nil
 }
+
+// DeleteMicrok8s stops microk8s.
+func DeleteMicrok8s(opts RunOptions) error {
+	return deleteCluster(NewMicrok8sProvider(), opts)
+}
@@ -2,20 +2,98 @@ package command
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 )
 
-func startMinikube(profile string, args ...string) error {
-	a := []string{"start", "-p", profile}
-	a = append(a, args...)
+// minikubeProvider is the ClusterProvider of minikube, the cluster name is the minikube profile.
+type minikubeProvider struct {
+	profile string
+}
+
+// NewMinikubeProvider returns the ClusterProvider of the minikube cluster with the provided profile.
+func NewMinikubeProvider(profile string) ClusterProvider {
+	return minikubeProvider{profile: profile}
+}
+
+func (m minikubeProvider) Name() string {
+	return ProviderMinikube
+}
+
+// Start starts minikube without waiting for its components, so the time until the cluster is ready is measured by WaitReady.
+// A --wait passed in the args takes precedence.
+func (m minikubeProvider) Start(args ...string) (ClusterStart, error) {
+	a := append([]string{"start", "-p", m.profile, "--wait=none"}, args...)
 	c := exec.Command("./minikube", a...)
 	if _, err := run(c); err != nil {
-		return fmt.Errorf("failed to start minikube: %v", err)
+		return ClusterStart{}, fmt.Errorf("failed to start minikube: %v", err)
 	}
 
+	return ClusterStart{Command: c.String()}, nil
+}
+
+func (m minikubeProvider) Delete() error {
+	return deleteMinikube(m.profile)
+}
+
+func (m minikubeProvider) Exists() (bool, error) {
+	profiles, err := ListMinikubeProfiles()
+	if err != nil {
+		return false, err
+	}
+	return contains(profiles, m.profile), nil
+}
+
+func (m minikubeProvider) LoadImage(image string) error {
+	c := exec.Command("./minikube", "-p", m.profile, "image", "load", image)
+	if _, err := run(c); err != nil {
+		return fmt.Errorf("failed to image load: %v", err)
+	}
 	return nil
 }
 
+// WaitReady waits using the host's kubectl, as minikube kubectl downloads kubectl on first use of every Kubernetes version.
+// minikube names the kubeconfig context after the profile.
+func (m minikubeProvider) WaitReady() error {
+	return waitReady("kubectl", "--context", m.profile)
+}
+
+func (m minikubeProvider) Runtime() (string, error) {
+	return crictlRuntime(m)
+}
+
+func (m minikubeProvider) ListImages() ([]string, error) {
+	o, err := output(exec.Command("./minikube", "-p", m.profile, "image", "ls"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image list: %v", err)
+	}
+	return lines(o), nil
+}
+
+func (m minikubeProvider) NodeExec(args ...string) (string, error) {
+	return minikubeNodeExec(m.profile, "", args...)
+}
+
+func startMinikube(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startCluster(NewMinikubeProvider(opts.Profile), minikubeRuntime(args), opts, args...)
+}
+
+// minikubeRuntime returns the runtime selected by the provided minikube start args, as crictl names it.
+func minikubeRuntime(args []string) string {
+	runtime := "docker"
+	for _, a := range args {
+		if r := strings.TrimPrefix(a, "--container-runtime="); r != a {
+			runtime = r
+		}
+	}
+	if runtime == "crio" {
+		return "cri-o"
+	}
+	return runtime
+}
+
 func enableRegistryAddon(profile string) error {
 	c := exec.Command("./minikube", "-p", profile, "addons", "enable", "registry")
 	if _, err := run(c); err != nil {
@@ -25,9 +103,26 @@ func enableRegistryAddon(profile string) error {
 	return nil
 }
 
-// deleteMinikube deletes the minikube cluster.
-func deleteMinikube() error {
-	c := exec.Command("./minikube", "delete", "--all")
+// DeleteMinikube deletes the minikube cluster of the provided profile along with the registry containers
+// the minikube methods may have started.
+func DeleteMinikube(opts RunOptions) error {
+	if err := deleteCluster(NewMinikubeProvider(opts.Profile), opts); err != nil {
+		return err
+	}
+
+	if err := deleteLocalRegistry(); err != nil {
+		return err
+	}
+
+	return deleteRegistryProxy()
+}
+
+// deleteMinikube deletes the minikube cluster of the provided profile.
+func deleteMinikube(profile string) error {
+	if _, err := os.Stat("./minikube"); os.IsNotExist(err) {
+		return nil
+	}
+	c := exec.Command("./minikube", "delete", "-p", profile)
 	if _, err := run(c); err != nil {
 		return fmt.Errorf("failed to delete minikube: %v", err)
 	}
@@ -49,23 +144,51 @@ func minikubeIP(profile string) (string, error) {
 }
 
 func verifyImage(image string, profile string) error {
-	verifyArgs := fmt.Sprintf("./minikube -p %s image ls | grep %s", profile, image)
-	verify := exec.Command("/bin/bash", "-c", verifyArgs)
-	o, err := run(verify)
-	if err != nil {
-		return fmt.Errorf("failed to get image list: %v", err)
+	return verifyClusterImage(NewMinikubeProvider(profile), image)
+}
+
+// ClearDockerAndMinikubeDockerCache clears out caching related to the docker-env method.
+func ClearDockerAndMinikubeDockerCache(opts RunOptions) error {
+	if err := ClearDockerCache(opts); err != nil {
+		return err
 	}
-	if string(o) == "" {
-		return fmt.Errorf("image was not found")
+	if err := ClearDockerEnvBuilderCache(opts); err != nil {
+		return err
 	}
+	return minikubeDockerSystemPrune(opts)
+}
 
-	return nil
+// minikubeHome returns the directory minikube stores its state in.
+func minikubeHome() (string, error) {
+	if home := os.Getenv("MINIKUBE_HOME"); home != "" {
+		return home, nil
+	}
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home dir: %v", err)
+	}
+	return filepath.Join(userHome, ".minikube"), nil
 }
 
-// ClearDockerAndMinikubeDockerCache clears out caching related to the docker-env method.
-func ClearDockerAndMinikubeDockerCache(profile string) error {
-	if err := DockerSystemPrune(); err != nil {
-		return err
+// ListMinikubeProfiles returns the names of all the existing minikube profiles.
+func ListMinikubeProfiles() ([]string, error) {
+	home, err := minikubeHome()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(home, "profiles"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list minikube profiles: %v", err)
+	}
+	profiles := []string{}
+	for _, e := range entries {
+		if e.IsDir() {
+			profiles = append(profiles, e.Name())
+		}
 	}
-	return minikubeDockerSystemPrune(profile)
+	return profiles, nil
 }
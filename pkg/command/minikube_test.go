@@ -0,0 +1,25 @@
+package command
+
+import "testing"
+
+func TestMinikubeRuntime(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "no args", want: "docker"},
+		{name: "other args", args: []string{"--driver=docker", "--nodes=2"}, want: "docker"},
+		{name: "containerd", args: []string{"--container-runtime=containerd"}, want: "containerd"},
+		{name: "cri-o", args: []string{"--container-runtime=cri-o"}, want: "cri-o"},
+		{name: "crio alias", args: []string{"--container-runtime=crio"}, want: "cri-o"},
+		{name: "last one wins", args: []string{"--container-runtime=containerd", "--driver=kvm2", "--container-runtime=cri-o"}, want: "cri-o"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := minikubeRuntime(tc.args); got != tc.want {
+				t.Errorf("minikubeRuntime(%q) = %q, want %q", tc.args, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package command
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// minikubeNodes returns the names of the nodes of the minikube cluster with the provided profile.
+func minikubeNodes(profile string) ([]string, error) {
+	o, err := output(exec.Command("./minikube", "-p", profile, "node", "list"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list minikube nodes: %v", err)
+	}
+	nodes := []string{}
+	for _, l := range lines(o) {
+		nodes = append(nodes, strings.Fields(l)[0])
+	}
+	return nodes, nil
+}
+
+// minikubeNodeExec runs the provided command as root on the provided node of the minikube cluster and returns its output,
+// an empty node runs it on the primary node.
+func minikubeNodeExec(profile string, node string, args ...string) (string, error) {
+	a := []string{"-p", profile, "ssh"}
+	if node != "" {
+		a = append(a, "-n", node)
+	}
+	a = append(a, "--", "sudo "+shellQuote(args))
+	o, err := output(exec.Command("./minikube", a...))
+	if err != nil {
+		return "", fmt.Errorf("failed to run command on minikube: %v", err)
+	}
+	return o, nil
+}
+
+// pullOnEveryNode pulls the provided image into the runtime of every node of the minikube cluster.
+func pullOnEveryNode(profile string, image string) error {
+	nodes, err := minikubeNodes(profile)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if _, err := minikubeNodeExec(profile, node, "crictl", "pull", image); err != nil {
+			return fmt.Errorf("failed to pull image on node %s: %v", node, err)
+		}
+	}
+	return nil
+}
+
+// verifyEveryNode returns an error if no image with the provided name is in the runtime of every node of the minikube cluster.
+func verifyEveryNode(profile string, name string) error {
+	nodes, err := minikubeNodes(profile)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		o, err := minikubeNodeExec(profile, node, "crictl", "images", "-o", "json")
+		if err != nil {
+			return err
+		}
+		images, err := parseCrictlImages(o)
+		if err != nil {
+			return err
+		}
+		if !containsImage(images, name) {
+			return fmt.Errorf("image was not found on node %s", node)
+		}
+	}
+	return nil
+}
+
+// nodeDockerEnv returns the shell prefix that points Docker at the daemon of the provided node of the minikube cluster.
+// minikube docker-env refuses to run against multi-node clusters, so the environment it would set is built directly,
+// using the certs minikube generates for the Docker daemon of every node.
+func nodeDockerEnv(profile string, node string) (string, error) {
+	ip, err := run(exec.Command("./minikube", "-p", profile, "ip", "-n", node))
+	if err != nil {
+		return "", fmt.Errorf("failed to get ip of node %s: %v", node, err)
+	}
+	home, err := minikubeHome()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("export DOCKER_TLS_VERIFY=1 DOCKER_HOST=tcp://%s:2376 DOCKER_CERT_PATH=%s && ", strings.TrimSpace(ip), filepath.Join(home, "certs")), nil
+}
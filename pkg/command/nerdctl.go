@@ -0,0 +1,42 @@
+package command
+
+var nerdctl = hostBuilder{
+	name:             "nerdctl",
+	insecurePushFlag: "--insecure-registry",
+	pruneCache:       pruneBuildkitCache,
+}
+
+// StartMinikubeRegistryNerdctl starts minikube for containerd registry with images pushed by nerdctl.
+func StartMinikubeRegistryNerdctl(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startMinikubeRegistryAddon(opts, "containerd", args...)
+}
+
+// RunNerdctlImageLoad builds the provided image using nerdctl, loads it via image load and returns the run time.
+func RunNerdctlImageLoad(image string, opts RunOptions) (Timing, error) {
+	return nerdctl.runArchive(image, NewMinikubeProvider(opts.Profile))
+}
+
+// RunNerdctlRegistry builds the provided image using nerdctl, pushes it to the registry addon and returns the run time.
+func RunNerdctlRegistry(image string, opts RunOptions) (Timing, error) {
+	return nerdctl.runRegistry(image, opts)
+}
+
+// RunNerdctlKind builds the provided image using nerdctl, loads it into kind from an archive and returns the run time.
+func RunNerdctlKind(image string, opts RunOptions) (Timing, error) {
+	return nerdctl.runArchive(image, NewKindProvider(opts.Profile))
+}
+
+// RunNerdctlK3d builds the provided image using nerdctl, imports it into k3d from an archive and returns the run time.
+func RunNerdctlK3d(image string, opts RunOptions) (Timing, error) {
+	return nerdctl.runArchive(image, NewK3dProvider(opts.Profile))
+}
+
+// PruneNerdctl removes the images created by the benchmark from nerdctl, along with the build cache.
+func PruneNerdctl() error {
+	return nerdctl.prune()
+}
+
+// ClearNerdctlCache clears out nerdctl's caching.
+func ClearNerdctlCache(opts RunOptions) error {
+	return PruneNerdctl()
+}
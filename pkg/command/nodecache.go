@@ -0,0 +1,199 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+// removeBenchmarkImagesArgs removes the images whose name contains benchmark from a node's runtime,
+// which every image transferred into the cluster by the benchmark has.
+var removeBenchmarkImagesArgs = []string{"sh", "-c", "crictl images | awk 'NR > 1 && $1 ~ /benchmark/ {print $3}' | sort -u | xargs -r crictl rmi"}
+
+// removeBenchmarkDockerImagesArgs removes the images whose name contains benchmark from a node's Docker,
+// which covers the images minikube image build creates without the benchmark label.
+var removeBenchmarkDockerImagesArgs = []string{"sh", "-c", "docker images --format '{{.Repository}} {{.ID}}' | awk '$1 ~ /benchmark/ {print $2}' | sort -u | xargs -r docker rmi -f"}
+
+// nodeCacheClearArgs returns the commands that clear out the image store of a node running the provided runtime,
+// along with the build cache of the builder minikube image build uses on that runtime.
+// On a reused cluster only the benchmark images are removed, as the other unused images and the build cache
+// can't be told apart from the cluster's own.
+func nodeCacheClearArgs(runtime string, reused bool) [][]string {
+	switch runtime {
+	case "containerd":
+		args := [][]string{
+			// images imported directly into containerd aren't always known to CRI, so they're removed with ctr first
+			{"sh", "-c", "ctr -n k8s.io images ls -q | grep benchmark | xargs -r ctr -n k8s.io images rm"},
+			removeBenchmarkImagesArgs,
+		}
+		if reused {
+			return args
+		}
+		return append(args,
+			[]string{"crictl", "rmi", "--prune"},
+			[]string{"sh", "-c", "if [ -S /run/buildkit/buildkitd.sock ]; then buildctl prune --all; fi"},
+		)
+	case "cri-o":
+		args := [][]string{removeBenchmarkImagesArgs}
+		if reused {
+			return args
+		}
+		return append(args,
+			[]string{"crictl", "rmi", "--prune"},
+			[]string{"sh", "-c", "if command -v podman > /dev/null; then podman system prune -a -f; fi"},
+		)
+	default:
+		if reused {
+			return [][]string{
+				removeBenchmarkDockerImagesArgs,
+				{"docker", "image", "prune", "-a", "-f", "--filter", "label=" + benchmarkLabel},
+			}
+		}
+		return [][]string{{"docker", "system", "prune", "-a", "--volumes", "-f"}}
+	}
+}
+
+// clearMinikubeNodeCache clears out the image store and build cache of every node of the minikube cluster according to its runtime,
+// then verifies that no benchmark image is left on any of them.
+func clearMinikubeNodeCache(opts RunOptions) error {
+	profile := opts.Profile
+	runtime, err := NewMinikubeProvider(profile).Runtime()
+	if err != nil {
+		return err
+	}
+	nodes, err := minikubeNodes(profile)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		nodeExec := func(args ...string) (string, error) {
+			return minikubeNodeExec(profile, node, args...)
+		}
+		if err := clearNodeCache(nodeExec, node, runtime, opts.ReuseCluster); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clearClusterNodeCache clears out the image store and build cache of the containerd node of the provided kind or k3d cluster,
+// then verifies that no benchmark image is left on it.
+func clearClusterNodeCache(p ClusterProvider, opts RunOptions) error {
+	return clearNodeCache(p.NodeExec, p.Name(), "containerd", opts.ReuseCluster)
+}
+
+// clearNodeCache clears out the image store and build cache of the provided node running the provided runtime,
+// running the commands with the provided nodeExec, then verifies that no benchmark image is left on it.
+func clearNodeCache(nodeExec func(args ...string) (string, error), node string, runtime string, reused bool) error {
+	for _, args := range nodeCacheClearArgs(runtime, reused) {
+		if _, err := nodeExec(args...); err != nil {
+			return fmt.Errorf("failed to clear %s cache on node %s: %v", runtime, node, err)
+		}
+	}
+
+	// verify
+	listArgs := [][]string{{"crictl", "images"}}
+	if runtime == "containerd" {
+		listArgs = append(listArgs, []string{"ctr", "-n", "k8s.io", "images", "ls", "-q"})
+	}
+	for _, args := range listArgs {
+		o, err := nodeExec(args...)
+		if err != nil {
+			return fmt.Errorf("failed to list images on node %s: %v", node, err)
+		}
+		for _, l := range lines(o) {
+			if strings.Contains(l, "benchmark") {
+				return fmt.Errorf("node %s still has a benchmark image after clearing its cache: %s", node, l)
+			}
+		}
+	}
+	return nil
+}
+
+// ClearDockerAndMinikubeNodeCache clears out Dockers caching on the host along with the image store and build cache inside minikube.
+func ClearDockerAndMinikubeNodeCache(opts RunOptions) error {
+	if err := ClearDockerCache(opts); err != nil {
+		return err
+	}
+	return clearMinikubeNodeCache(opts)
+}
+
+// ClearLocalRegistryAndDockerAndMinikubeNodeCache clears out the local registry along with Dockers caching on the host
+// and the image store and build cache inside minikube.
+func ClearLocalRegistryAndDockerAndMinikubeNodeCache(opts RunOptions) error {
+	return resetLocalRegistry(opts, ClearDockerAndMinikubeNodeCache)
+}
+
+// ClearDockerEnvAndMinikubeNodeCache clears out the builder caching related to the docker-env method
+// along with the image store and build cache inside minikube.
+func ClearDockerEnvAndMinikubeNodeCache(opts RunOptions) error {
+	if err := ClearDockerEnvBuilderCache(opts); err != nil {
+		return err
+	}
+	return clearMinikubeNodeCache(opts)
+}
+
+// ClearNerdctlAndMinikubeNodeCache clears out nerdctl's caching along with the image store and build cache inside minikube.
+func ClearNerdctlAndMinikubeNodeCache(opts RunOptions) error {
+	if err := ClearNerdctlCache(opts); err != nil {
+		return err
+	}
+	return clearMinikubeNodeCache(opts)
+}
+
+// ClearPodmanAndMinikubeNodeCache clears out podman's caching along with the image store and build cache inside minikube.
+func ClearPodmanAndMinikubeNodeCache(opts RunOptions) error {
+	if err := ClearPodmanCache(opts); err != nil {
+		return err
+	}
+	return clearMinikubeNodeCache(opts)
+}
+
+// ClearRegistryAddonAndDockerAndMinikubeNodeCache clears out the registry addon along with Dockers caching on the host
+// and the image store and build cache inside minikube.
+func ClearRegistryAddonAndDockerAndMinikubeNodeCache(opts RunOptions) error {
+	return resetRegistryAddon(opts, ClearDockerAndMinikubeNodeCache)
+}
+
+// ClearRegistryAddonAndNerdctlAndMinikubeNodeCache clears out the registry addon along with nerdctl's caching
+// and the image store and build cache inside minikube.
+func ClearRegistryAddonAndNerdctlAndMinikubeNodeCache(opts RunOptions) error {
+	return resetRegistryAddon(opts, ClearNerdctlAndMinikubeNodeCache)
+}
+
+// ClearRegistryAddonAndPodmanAndMinikubeNodeCache clears out the registry addon along with podman's caching
+// and the image store and build cache inside minikube.
+func ClearRegistryAddonAndPodmanAndMinikubeNodeCache(opts RunOptions) error {
+	return resetRegistryAddon(opts, ClearPodmanAndMinikubeNodeCache)
+}
+
+// ClearNerdctlAndKindNodeCache clears out nerdctl's caching along with the image store and build cache of the kind node.
+func ClearNerdctlAndKindNodeCache(opts RunOptions) error {
+	if err := ClearNerdctlCache(opts); err != nil {
+		return err
+	}
+	return clearClusterNodeCache(NewKindProvider(opts.Profile), opts)
+}
+
+// ClearNerdctlAndK3dNodeCache clears out nerdctl's caching along with the image store and build cache of the k3d node.
+func ClearNerdctlAndK3dNodeCache(opts RunOptions) error {
+	if err := ClearNerdctlCache(opts); err != nil {
+		return err
+	}
+	return clearClusterNodeCache(NewK3dProvider(opts.Profile), opts)
+}
+
+// ClearPodmanAndKindNodeCache clears out podman's caching along with the image store and build cache of the kind node.
+func ClearPodmanAndKindNodeCache(opts RunOptions) error {
+	if err := ClearPodmanCache(opts); err != nil {
+		return err
+	}
+	return clearClusterNodeCache(NewKindProvider(opts.Profile), opts)
+}
+
+// ClearPodmanAndK3dNodeCache clears out podman's caching along with the image store and build cache of the k3d node.
+func ClearPodmanAndK3dNodeCache(opts RunOptions) error {
+	if err := ClearPodmanCache(opts); err != nil {
+		return err
+	}
+	return clearClusterNodeCache(NewK3dProvider(opts.Profile), opts)
+}
@@ -0,0 +1,77 @@
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNodeCacheClearArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		runtime string
+		reused  bool
+		// want contains the commands, with their args joined by spaces.
+		want []string
+	}{
+		{
+			name:    "containerd",
+			runtime: "containerd",
+			want: []string{
+				"sh -c ctr -n k8s.io images ls -q | grep benchmark | xargs -r ctr -n k8s.io images rm",
+				strings.Join(removeBenchmarkImagesArgs, " "),
+				"crictl rmi --prune",
+				"sh -c if [ -S /run/buildkit/buildkitd.sock ]; then buildctl prune --all; fi",
+			},
+		},
+		{
+			name:    "reused containerd",
+			runtime: "containerd",
+			reused:  true,
+			want: []string{
+				"sh -c ctr -n k8s.io images ls -q | grep benchmark | xargs -r ctr -n k8s.io images rm",
+				strings.Join(removeBenchmarkImagesArgs, " "),
+			},
+		},
+		{
+			name:    "cri-o",
+			runtime: "cri-o",
+			want: []string{
+				strings.Join(removeBenchmarkImagesArgs, " "),
+				"crictl rmi --prune",
+				"sh -c if command -v podman > /dev/null; then podman system prune -a -f; fi",
+			},
+		},
+		{
+			name:    "reused cri-o",
+			runtime: "cri-o",
+			reused:  true,
+			want:    []string{strings.Join(removeBenchmarkImagesArgs, " ")},
+		},
+		{
+			name:    "docker",
+			runtime: "docker",
+			want:    []string{"docker system prune -a --volumes -f"},
+		},
+		{
+			name:    "reused docker",
+			runtime: "docker",
+			reused:  true,
+			want: []string{
+				strings.Join(removeBenchmarkDockerImagesArgs, " "),
+				"docker image prune -a -f --filter label=" + benchmarkLabel,
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := []string{}
+			for _, args := range nodeCacheClearArgs(tc.runtime, tc.reused) {
+				got = append(got, strings.Join(args, " "))
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("nodeCacheClearArgs(%q, %t) = %q, want %q", tc.runtime, tc.reused, got, tc.want)
+			}
+		})
+	}
+}
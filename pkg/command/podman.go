@@ -0,0 +1,43 @@
+package command
+
+var podman = hostBuilder{
+	name:             "podman",
+	insecurePushFlag: "--tls-verify=false",
+	// podman caches intermediate images, which are left dangling once the benchmark's images are removed
+	pruneCache: pruneDanglingImages,
+}
+
+// StartMinikubeRegistryPodman starts minikube for crio registry with images pushed by podman.
+func StartMinikubeRegistryPodman(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startMinikubeRegistryAddon(opts, "cri-o", args...)
+}
+
+// RunPodmanImageLoad builds the provided image using podman, loads it via image load and returns the run time.
+func RunPodmanImageLoad(image string, opts RunOptions) (Timing, error) {
+	return podman.runArchive(image, NewMinikubeProvider(opts.Profile))
+}
+
+// RunPodmanRegistry builds the provided image using podman, pushes it to the registry addon and returns the run time.
+func RunPodmanRegistry(image string, opts RunOptions) (Timing, error) {
+	return podman.runRegistry(image, opts)
+}
+
+// RunPodmanKind builds the provided image using podman, loads it into kind from an archive and returns the run time.
+func RunPodmanKind(image string, opts RunOptions) (Timing, error) {
+	return podman.runArchive(image, NewKindProvider(opts.Profile))
+}
+
+// RunPodmanK3d builds the provided image using podman, imports it into k3d from an archive and returns the run time.
+func RunPodmanK3d(image string, opts RunOptions) (Timing, error) {
+	return podman.runArchive(image, NewK3dProvider(opts.Profile))
+}
+
+// PrunePodman removes the images created by the benchmark from podman, along with the build cache.
+func PrunePodman() error {
+	return podman.prune()
+}
+
+// ClearPodmanCache clears out podman's caching.
+func ClearPodmanCache(opts RunOptions) error {
+	return PrunePodman()
+}
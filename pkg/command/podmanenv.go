@@ -0,0 +1,51 @@
+package command
+
+import (
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// StartMinikubePodmanEnv starts minikube for crio podman-env.
+func StartMinikubePodmanEnv(opts RunOptions, args ...string) (ClusterStart, error) {
+	arguments := append([]string{"--container-runtime=cri-o"}, args...)
+	return startMinikube(opts, arguments...)
+}
+
+// RunPodmanEnv builds the provided image using the podman-env method and returns the run time.
+func RunPodmanEnv(image string, opts RunOptions) (Timing, error) {
+	// build
+	buildArgs := fmt.Sprintf("eval $(./minikube -p %s podman-env) && podman --remote build -t benchmark-podman-env -f testdata/Dockerfile.%s .", opts.Profile, image)
+	build := exec.Command("/bin/bash", "-c", buildArgs)
+	start := time.Now()
+	if _, err := run(build); err != nil {
+		return Timing{}, fmt.Errorf("failed to build via podman-env: %v", err)
+	}
+	elapsed := time.Now().Sub(start)
+
+	// verify
+	if err := verifyImage("benchmark-podman-env", opts.Profile); err != nil {
+		return Timing{}, fmt.Errorf("image was not found after podman-env: %v", err)
+	}
+
+	return Timing{Total: elapsed.Seconds(), Transfer: elapsed.Seconds()}, nil
+}
+
+// minikubePodmanSystemPrune does a podman system prune inside minikube.
+func minikubePodmanSystemPrune(profile string) error {
+	args := fmt.Sprintf("./minikube -p %s ssh -- sudo podman system prune -a --volumes -f", profile)
+	c := exec.Command("/bin/bash", "-c", args)
+	if _, err := run(c); err != nil {
+		return fmt.Errorf("failed to minikube podman prune: %v", err)
+	}
+	return nil
+}
+
+// ClearMinikubePodmanCache clears out caching related to the podman-env method,
+// only the benchmark images are removed from a reused cluster.
+func ClearMinikubePodmanCache(opts RunOptions) error {
+	if opts.ReuseCluster {
+		return clearMinikubeNodeCache(opts)
+	}
+	return minikubePodmanSystemPrune(opts.Profile)
+}
@@ -0,0 +1,355 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The names of the cluster providers, which the start args are keyed by.
+const (
+	ProviderMinikube = "minikube"
+	ProviderKind     = "kind"
+	ProviderK3d      = "k3d"
+	ProviderMicrok8s = "microk8s"
+)
+
+// ClusterStart describes how the cluster of a method was started.
+type ClusterStart struct {
+	// Command is the command the cluster was started with.
+	Command string
+	// Create is the time taken by the provider to create and start the cluster, without the setup the method does afterwards.
+	Create time.Duration
+	// Ready is the time taken by the API server and every node to become ready once the cluster was started.
+	Ready time.Duration
+}
+
+// KubernetesVersionArgs returns the start args of the provided provider that create a cluster running the provided Kubernetes version.
+// It returns nil if the provider's Kubernetes version can't be selected when starting it.
+func KubernetesVersionArgs(provider string, version string) []string {
+	if provider == ProviderMinikube {
+		return []string{"--kubernetes-version=" + kubernetesVersion(version)}
+	}
+	if image := kubernetesVersionImage(provider, version); image != "" {
+		return []string{"--image", image}
+	}
+	return nil
+}
+
+// CheckKubernetesVersion returns an error if the node image the provided provider runs the provided Kubernetes version with
+// doesn't exist, providers that don't select the version via an image aren't checked.
+func CheckKubernetesVersion(provider string, version string) error {
+	image := kubernetesVersionImage(provider, version)
+	if image == "" {
+		return nil
+	}
+	c := exec.Command("docker", "manifest", "inspect", image)
+	if _, err := run(c); err != nil {
+		return fmt.Errorf("%s can't run Kubernetes %s, its node image %s couldn't be found: %v", provider, version, image, err)
+	}
+	return nil
+}
+
+// kubernetesVersion returns the provided Kubernetes version prefixed with v, as it's tagged.
+func kubernetesVersion(version string) string {
+	if !strings.HasPrefix(version, "v") {
+		return "v" + version
+	}
+	return version
+}
+
+// kubernetesVersionImage returns the node image the provided provider runs the provided Kubernetes version with,
+// it returns an empty string if the provider doesn't select the version via an image.
+func kubernetesVersionImage(provider string, version string) string {
+	switch provider {
+	case ProviderKind:
+		return "kindest/node:" + kubernetesVersion(version)
+	case ProviderK3d:
+		// k3s images are tagged with the k3s release on top of the Kubernetes version, the first release is used
+		return "rancher/k3s:" + kubernetesVersion(version) + "-k3s1"
+	default:
+		return ""
+	}
+}
+
+// ClusterProvider creates and manages the clusters the images are transferred into, each provider honors the cluster name it's created with.
+type ClusterProvider interface {
+	// Name returns the name of the tool providing the cluster.
+	Name() string
+	// Start creates and starts the cluster, passing the provided args to the tool.
+	Start(args ...string) (ClusterStart, error)
+	// Delete deletes the cluster, if it exists.
+	Delete() error
+	// Exists returns whether the cluster exists.
+	Exists() (bool, error)
+	// LoadImage loads the provided image from the Docker daemon into the cluster,
+	// images ending with .tar are loaded from the archive at that path instead.
+	LoadImage(image string) error
+	// WaitReady waits for the cluster's API server to be ready and every node to be Ready.
+	WaitReady() error
+	// Runtime returns the name of the cluster's container runtime, as reported by crictl.
+	Runtime() (string, error)
+	// ListImages returns the images in the cluster's runtime.
+	ListImages() ([]string, error)
+	// NodeExec runs the provided command as root on the cluster's node and returns its output.
+	NodeExec(args ...string) (string, error)
+}
+
+// readyTimeout is how long a cluster is waited for to become ready.
+const readyTimeout = 5 * time.Minute
+
+// waitReady waits for the API server reached with the provided kubectl command to be ready, then for every node to be Ready.
+func waitReady(kubectl ...string) error {
+	readyz := append(append([]string{}, kubectl...), "get", "--raw", "/readyz")
+	deadline := time.Now().Add(readyTimeout)
+	for {
+		_, err := run(exec.Command(readyz[0], readyz[1:]...))
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("API server didn't become ready: %v", err)
+		}
+		time.Sleep(time.Second)
+	}
+
+	wait := append(append([]string{}, kubectl...), "wait", "--for=condition=Ready", "nodes", "--all", fmt.Sprintf("--timeout=%s", time.Until(deadline).Round(time.Second)))
+	if _, err := run(exec.Command(wait[0], wait[1:]...)); err != nil {
+		return fmt.Errorf("nodes didn't become ready: %v", err)
+	}
+	return nil
+}
+
+// isArchive returns whether the provided image refers to an image archive rather than an image in the Docker daemon.
+func isArchive(image string) bool {
+	return strings.HasSuffix(image, ".tar")
+}
+
+// contains returns whether the provided list contains the provided element.
+func contains(list []string, element string) bool {
+	for _, e := range list {
+		if e == element {
+			return true
+		}
+	}
+	return false
+}
+
+// shellQuote quotes the provided args so they are passed as is through a shell.
+func shellQuote(args []string) string {
+	quoted := []string{}
+	for _, a := range args {
+		quoted = append(quoted, "'"+strings.ReplaceAll(a, "'", `'\''`)+"'")
+	}
+	return strings.Join(quoted, " ")
+}
+
+// crictlImages returns the images in the runtime of the provided cluster, as reported by crictl on its node.
+func crictlImages(p ClusterProvider) ([]string, error) {
+	o, err := p.NodeExec("crictl", "images", "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s images: %v", p.Name(), err)
+	}
+	return parseCrictlImages(o)
+}
+
+// parseCrictlImages returns the repository and tag of every image in the provided output of crictl images -o json.
+func parseCrictlImages(o string) ([]string, error) {
+	var list struct {
+		Images []struct {
+			RepoTags []string `json:"repoTags"`
+		} `json:"images"`
+	}
+	if err := json.Unmarshal([]byte(o), &list); err != nil {
+		return nil, fmt.Errorf("failed to parse crictl images: %v", err)
+	}
+	images := []string{}
+	for _, i := range list.Images {
+		images = append(images, i.RepoTags...)
+	}
+	return images, nil
+}
+
+// crictlImageID returns the ID of the provided image in the runtime of the provided cluster, as reported by crictl on its node.
+func crictlImageID(p ClusterProvider, image string) (string, error) {
+	o, err := p.NodeExec("crictl", "inspecti", "-o", "json", image)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect %s image: %v", p.Name(), err)
+	}
+	var inspect struct {
+		Status struct {
+			ID string `json:"id"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(o), &inspect); err != nil {
+		return "", fmt.Errorf("failed to parse crictl inspecti: %v", err)
+	}
+	return inspect.Status.ID, nil
+}
+
+// crictlRuntime returns the name of the runtime of the provided cluster, as reported by crictl on its node.
+func crictlRuntime(p ClusterProvider) (string, error) {
+	o, err := p.NodeExec("crictl", "version")
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s runtime: %v", p.Name(), err)
+	}
+	for _, l := range lines(o) {
+		if name := strings.TrimPrefix(l, "RuntimeName:"); name != l {
+			return strings.TrimSpace(name), nil
+		}
+	}
+	return "", fmt.Errorf("failed to find %s runtime in: %s", p.Name(), o)
+}
+
+// startCluster starts the provided cluster with the provided args, unless the cluster is reused,
+// in which case it's only validated to run the provided runtime, then waits for it to be ready
+// so the method's setup runs against a ready cluster.
+func startCluster(p ClusterProvider, runtime string, opts RunOptions, args ...string) (ClusterStart, error) {
+	start := time.Now()
+	var s ClusterStart
+	var err error
+	if opts.ReuseCluster {
+		s, err = attachCluster(p, runtime)
+	} else {
+		s, err = p.Start(args...)
+	}
+	s.Create = time.Now().Sub(start)
+	if err != nil {
+		return s, err
+	}
+
+	start = time.Now()
+	if err := p.WaitReady(); err != nil {
+		return s, fmt.Errorf("%s didn't become ready: %v", p.Name(), err)
+	}
+	s.Ready = time.Now().Sub(start)
+	return s, nil
+}
+
+// deleteCluster deletes the provided cluster, unless it's reused.
+func deleteCluster(p ClusterProvider, opts RunOptions) error {
+	if opts.ReuseCluster {
+		return nil
+	}
+	return p.Delete()
+}
+
+// attachCluster validates that the provided cluster exists and runs the provided runtime, so it can be reused instead of started.
+func attachCluster(p ClusterProvider, runtime string) (ClusterStart, error) {
+	exists, err := p.Exists()
+	if err != nil {
+		return ClusterStart{}, err
+	}
+	if !exists {
+		return ClusterStart{}, fmt.Errorf("can't reuse %s cluster, it doesn't exist", p.Name())
+	}
+	r, err := p.Runtime()
+	if err != nil {
+		return ClusterStart{}, fmt.Errorf("can't reuse %s cluster, it isn't running: %v", p.Name(), err)
+	}
+	if r != runtime {
+		return ClusterStart{}, fmt.Errorf("can't reuse %s cluster, it runs %s instead of %s", p.Name(), r, runtime)
+	}
+	return ClusterStart{Command: "reused existing " + p.Name() + " cluster"}, nil
+}
+
+// verifyClusterImage returns an error if no image in the provided cluster's runtime has the provided name.
+func verifyClusterImage(p ClusterProvider, name string) error {
+	images, err := p.ListImages()
+	if err != nil {
+		return err
+	}
+	if !containsImage(images, name) {
+		return fmt.Errorf("image was not found")
+	}
+	return nil
+}
+
+// containsImage returns whether any of the provided image references has the provided name, whatever its registry and tag.
+func containsImage(images []string, name string) bool {
+	for _, i := range images {
+		if imageName(i) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// imageName returns the name of the provided image reference, which is its last path component without the tag or digest.
+func imageName(ref string) string {
+	ref = strings.SplitN(ref, "@", 2)[0]
+	if i := strings.LastIndex(ref, "/"); i != -1 {
+		ref = ref[i+1:]
+	}
+	return strings.SplitN(ref, ":", 2)[0]
+}
+
+// runLoadImage builds the provided image with Docker, loads it into the provided cluster from the Docker daemon and returns the run time.
+func runLoadImage(p ClusterProvider, tag string, image string, opts RunOptions) (Timing, error) {
+	// build
+	build := dockerBuild(opts.Builder, tag, image)
+	start := time.Now()
+	if _, err := run(build); err != nil {
+		return Timing{}, fmt.Errorf("failed to build via %s: %v", p.Name(), err)
+	}
+
+	// load
+	transferStart := time.Now()
+	if err := p.LoadImage(tag + ":latest"); err != nil {
+		return Timing{}, err
+	}
+	elapsed := time.Now().Sub(start)
+	transfer := time.Now().Sub(transferStart)
+
+	// verify
+	if err := verifyClusterImage(p, tag); err != nil {
+		return Timing{}, fmt.Errorf("image was not found after %s load: %v", p.Name(), err)
+	}
+
+	return Timing{Total: elapsed.Seconds(), Transfer: transfer.Seconds()}, nil
+}
+
+// runLoadArchive builds the provided image with Docker, saves it to an archive, loads it into the provided cluster from
+// the archive and returns the run time.
+func runLoadArchive(p ClusterProvider, tag string, image string, opts RunOptions) (Timing, error) {
+	dir, err := archiveDir()
+	if err != nil {
+		return Timing{}, err
+	}
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, tag+".tar")
+
+	// build
+	build := dockerBuild(opts.Builder, tag, image)
+	start := time.Now()
+	if _, err := run(build); err != nil {
+		return Timing{}, fmt.Errorf("failed to build via %s: %v", p.Name(), err)
+	}
+
+	// save
+	save := exec.Command("docker", "save", "-o", archive, tag)
+	saveStart := time.Now()
+	if _, err := run(save); err != nil {
+		return Timing{}, fmt.Errorf("failed to save image via %s: %v", p.Name(), err)
+	}
+	saved := time.Now().Sub(saveStart)
+
+	// load
+	transferStart := time.Now()
+	if err := p.LoadImage(archive); err != nil {
+		return Timing{}, err
+	}
+	elapsed := time.Now().Sub(start)
+	transfer := time.Now().Sub(transferStart)
+
+	// verify
+	if err := verifyClusterImage(p, tag); err != nil {
+		return Timing{}, fmt.Errorf("image was not found after %s load: %v", p.Name(), err)
+	}
+
+	return Timing{Total: elapsed.Seconds(), Transfer: transfer.Seconds(), Save: saved.Seconds()}, nil
+}
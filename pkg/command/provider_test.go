@@ -0,0 +1,26 @@
+package command
+
+import "testing"
+
+func TestImageName(t *testing.T) {
+	tests := []struct {
+		name string
+		ref  string
+		want string
+	}{
+		{name: "name only", ref: "benchmark-image", want: "benchmark-image"},
+		{name: "tag", ref: "benchmark-image:latest", want: "benchmark-image"},
+		{name: "registry", ref: "docker.io/library/benchmark-image:latest", want: "benchmark-image"},
+		{name: "registry with port", ref: "localhost:5000/benchmark-image", want: "benchmark-image"},
+		{name: "registry with port and tag", ref: "localhost:5000/benchmark-image:latest", want: "benchmark-image"},
+		{name: "digest", ref: "localhost:5000/benchmark-image@sha256:0123456789abcdef", want: "benchmark-image"},
+		{name: "tag and digest", ref: "benchmark-image:latest@sha256:0123456789abcdef", want: "benchmark-image"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := imageName(tc.ref); got != tc.want {
+				t.Errorf("imageName(%q) = %q, want %q", tc.ref, got, tc.want)
+			}
+		})
+	}
+}
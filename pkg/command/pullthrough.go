@@ -0,0 +1,259 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// pullThroughRepo is the repository the image is pushed to for the pull-through methods.
+	pullThroughRepo = "benchmark-pull-through"
+	// localRegistryFromNetwork is the address the local registry is reachable at from a Docker network it's connected to.
+	localRegistryFromNetwork = "http://" + localRegistryName + ":5000"
+)
+
+// kindRegistryPatch is the containerd config patch that configures kind's containerd to pull images tagged with the local registry
+// address from the local registry container.
+var kindRegistryPatch = fmt.Sprintf(`[plugins."io.containerd.grpc.v1.cri".registry.mirrors."%s"]
+  endpoint = ["%s"]`, localRegistry, localRegistryFromNetwork)
+
+// kindConfigHeader is the header of a kind cluster config.
+const kindConfigHeader = `kind: Cluster
+apiVersion: kind.x-k8s.io/v1alpha4
+`
+
+// k3dRegistryConfig configures k3s to pull images tagged with the local registry address from the local registry container.
+var k3dRegistryConfig = fmt.Sprintf(`mirrors:
+  "%s":
+    endpoint:
+      - %s
+`, localRegistry, localRegistryFromNetwork)
+
+// StartMinikubePullThroughDocker starts minikube for docker pull-through.
+func StartMinikubePullThroughDocker(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startMinikubeImagePull(opts, "docker", args...)
+}
+
+// StartMinikubePullThroughContainerd starts minikube for containerd pull-through.
+func StartMinikubePullThroughContainerd(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startMinikubeImagePull(opts, "containerd", args...)
+}
+
+// StartMinikubePullThroughCrio starts minikube for crio pull-through.
+func StartMinikubePullThroughCrio(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startMinikubeImagePull(opts, "cri-o", args...)
+}
+
+// StartKindPullThrough starts kind with its containerd configured to pull from the local registry.
+func StartKindPullThrough(opts RunOptions, args ...string) (ClusterStart, error) {
+	if err := startLocalRegistry(); err != nil {
+		return ClusterStart{}, err
+	}
+
+	dir, err := os.MkdirTemp("", "benchmark-kind-registry")
+	if err != nil {
+		return ClusterStart{}, fmt.Errorf("failed to create kind config dir: %v", err)
+	}
+	// kind only reads the config when creating the cluster
+	defer os.RemoveAll(dir)
+	arguments, err := kindRegistryArgs(args, dir)
+	if err != nil {
+		return ClusterStart{}, err
+	}
+	s, err := startCluster(NewKindProvider(opts.Profile), "containerd", opts, arguments...)
+	if err != nil {
+		return s, err
+	}
+
+	return s, connectLocalRegistry("kind")
+}
+
+// kindRegistryArgs returns the provided kind args with the registry patch added to the config passed via --config,
+// or to a new config if none was passed. The patched config is written to the provided dir.
+func kindRegistryArgs(args []string, dir string) ([]string, error) {
+	config := []byte(kindConfigHeader)
+	arguments := []string{}
+	for i := 0; i < len(args); i++ {
+		if args[i] != "--config" || i+1 == len(args) {
+			arguments = append(arguments, args[i])
+			continue
+		}
+		b, err := os.ReadFile(args[i+1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kind config: %v", err)
+		}
+		config = b
+		i++
+	}
+
+	patched, err := patchKindConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, "kind-config.yaml")
+	if err := os.WriteFile(path, patched, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write kind config: %v", err)
+	}
+	return append([]string{"--config", path}, arguments...), nil
+}
+
+// patchKindConfig returns the provided kind config with the registry patch added to its containerdConfigPatches,
+// kind only accepts a single containerdConfigPatches list so the patch is added to the existing one.
+func patchKindConfig(config []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(config, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse kind config: %v", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("failed to patch kind config, it isn't a mapping")
+	}
+
+	root := doc.Content[0]
+	patch := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Style: yaml.LiteralStyle, Value: kindRegistryPatch}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "containerdConfigPatches" {
+			continue
+		}
+		patches := root.Content[i+1]
+		if patches.Kind != yaml.SequenceNode {
+			return nil, fmt.Errorf("failed to patch kind config, its containerdConfigPatches isn't a list")
+		}
+		// a flow style list can't hold the multi-line patch as a block
+		patches.Style &^= yaml.FlowStyle
+		patches.Content = append(patches.Content, patch)
+		return yaml.Marshal(&doc)
+	}
+
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "containerdConfigPatches"}
+	patches := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{patch}}
+	root.Content = append(root.Content, key, patches)
+	return yaml.Marshal(&doc)
+}
+
+// StartK3dPullThrough starts k3d with k3s configured to pull from the local registry.
+func StartK3dPullThrough(opts RunOptions, args ...string) (ClusterStart, error) {
+	if err := startLocalRegistry(); err != nil {
+		return ClusterStart{}, err
+	}
+
+	dir, err := os.MkdirTemp("", "benchmark-k3d-registry")
+	if err != nil {
+		return ClusterStart{}, fmt.Errorf("failed to create k3d registry config dir: %v", err)
+	}
+	// k3d copies the registry config into the nodes when creating the cluster
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "registries.yaml")
+	if err := os.WriteFile(path, []byte(k3dRegistryConfig), 0644); err != nil {
+		return ClusterStart{}, fmt.Errorf("failed to write k3d registry config: %v", err)
+	}
+	arguments := append([]string{"--registry-config", path}, args...)
+	s, err := startCluster(NewK3dProvider(opts.Profile), "containerd", opts, arguments...)
+	if err != nil {
+		return s, err
+	}
+
+	return s, connectLocalRegistry(k3dNetwork(opts.Profile))
+}
+
+// deleteRegistryConfigs removes the registry config dirs left over by a pull-through method that was killed while creating its cluster.
+func deleteRegistryConfigs() error {
+	dirs, err := filepath.Glob(filepath.Join(os.TempDir(), "benchmark-*-registry*"))
+	if err != nil {
+		return fmt.Errorf("failed to list registry config dirs: %v", err)
+	}
+	for _, dir := range dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to remove registry config dir: %v", err)
+		}
+	}
+	return nil
+}
+
+// connectLocalRegistry connects the local registry container to the provided Docker network, so the cluster nodes on it can reach it.
+func connectLocalRegistry(network string) error {
+	c := exec.Command("docker", "network", "connect", network, localRegistryName)
+	if _, err := run(c); err != nil {
+		return fmt.Errorf("failed to connect local registry to %s network: %v", network, err)
+	}
+
+	return nil
+}
+
+// RunPullThroughMinikube builds the provided image, pushes it to the local registry, pulls it from minikube's runtime and returns the run time.
+func RunPullThroughMinikube(image string, opts RunOptions) (Timing, error) {
+	return runPullThrough(image, opts, NewMinikubeProvider(opts.Profile), localRegistryFromMinikube)
+}
+
+// RunPullThroughKind builds the provided image, pushes it to the local registry, pulls it from kind's containerd and returns the run time.
+func RunPullThroughKind(image string, opts RunOptions) (Timing, error) {
+	return runPullThrough(image, opts, NewKindProvider(opts.Profile), localRegistry)
+}
+
+// RunPullThroughK3d builds the provided image, pushes it to the local registry, pulls it from k3s's containerd and returns the run time.
+func RunPullThroughK3d(image string, opts RunOptions) (Timing, error) {
+	return runPullThrough(image, opts, NewK3dProvider(opts.Profile), localRegistry)
+}
+
+// runPullThrough builds the provided image, pushes it to the local registry and pulls it from the provided cluster's runtime,
+// using the provided address of the local registry.
+func runPullThrough(image string, opts RunOptions, p ClusterProvider, registry string) (Timing, error) {
+	build, push, _, err := pushToLocalRegistry(image, pullThroughRepo, opts)
+	if err != nil {
+		return Timing{}, fmt.Errorf("failed to prepare pull-through: %v", err)
+	}
+
+	// pull
+	start := time.Now()
+	if _, err := p.NodeExec("crictl", "pull", registry+"/"+pullThroughRepo); err != nil {
+		return Timing{}, fmt.Errorf("failed to pull via pull-through: %v", err)
+	}
+	transfer := push + time.Now().Sub(start)
+
+	// verify
+	if err := verifyClusterImage(p, pullThroughRepo); err != nil {
+		return Timing{}, fmt.Errorf("image was not found after pull-through: %v", err)
+	}
+
+	return Timing{Total: (build + transfer).Seconds(), Transfer: transfer.Seconds()}, nil
+}
+
+// removeNodeImageArgs is the shell command that removes the provided image from a node's runtime, if it exists.
+func removeNodeImageArgs(image string) string {
+	return fmt.Sprintf("if crictl inspecti %[1]s > /dev/null 2>&1; then crictl rmi %[1]s; fi", image)
+}
+
+// ClearPullThroughMinikubeCache clears out caching related to the minikube pull-through methods.
+func ClearPullThroughMinikubeCache(opts RunOptions) error {
+	return clearPullThroughNode(opts, NewMinikubeProvider(opts.Profile), localRegistryFromMinikube, "")
+}
+
+// ClearPullThroughKindCache clears out caching related to the kind pull-through method.
+func ClearPullThroughKindCache(opts RunOptions) error {
+	return clearPullThroughNode(opts, NewKindProvider(opts.Profile), localRegistry, "kind")
+}
+
+// ClearPullThroughK3dCache clears out caching related to the k3d pull-through method.
+func ClearPullThroughK3dCache(opts RunOptions) error {
+	return clearPullThroughNode(opts, NewK3dProvider(opts.Profile), localRegistry, k3dNetwork(opts.Profile))
+}
+
+// clearPullThroughNode removes the image pulled from the provided registry address from the provided cluster's runtime
+// and resets the local registry, reconnecting it to the provided network afterwards, if any.
+func clearPullThroughNode(opts RunOptions, p ClusterProvider, registry string, network string) error {
+	if _, err := p.NodeExec("sh", "-c", removeNodeImageArgs(registry+"/"+pullThroughRepo)); err != nil {
+		return fmt.Errorf("failed to remove image from %s: %v", p.Name(), err)
+	}
+	if err := ClearLocalRegistryAndDockerCache(opts); err != nil {
+		return err
+	}
+	if network == "" {
+		return nil
+	}
+	return connectLocalRegistry(network)
+}
@@ -0,0 +1,92 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestKindRegistryArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		// config is the content of the config passed via --config, none is passed if it's empty.
+		config      string
+		wantPatches []string
+		wantNodes   int
+	}{
+		{
+			name:        "no config",
+			wantPatches: []string{kindRegistryPatch},
+		},
+		{
+			name:        "config without patches",
+			config:      "kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\nnodes:\n- role: control-plane\n- role: worker\n",
+			wantPatches: []string{kindRegistryPatch},
+			wantNodes:   2,
+		},
+		{
+			name:        "existing patches",
+			config:      "kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\ncontainerdConfigPatches:\n- |-\n  [plugins.\"io.containerd.grpc.v1.cri\"]\n    sandbox_image = \"pause\"\nnodes:\n- role: control-plane\n",
+			wantPatches: []string{"[plugins.\"io.containerd.grpc.v1.cri\"]\n  sandbox_image = \"pause\"", kindRegistryPatch},
+			wantNodes:   1,
+		},
+		{
+			name:        "flow style patches",
+			config:      "kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\ncontainerdConfigPatches: [\"[plugins]\"]\n",
+			wantPatches: []string{"[plugins]", kindRegistryPatch},
+		},
+		{
+			name:        "CRLF line endings",
+			config:      "kind: Cluster\r\napiVersion: kind.x-k8s.io/v1alpha4\r\ncontainerdConfigPatches:\r\n- \"[plugins]\"\r\n",
+			wantPatches: []string{"[plugins]", kindRegistryPatch},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			args := []string{"--retain"}
+			if tc.config != "" {
+				path := filepath.Join(dir, "user-config.yaml")
+				if err := os.WriteFile(path, []byte(tc.config), 0644); err != nil {
+					t.Fatal(err)
+				}
+				args = append(args, "--config", path)
+			}
+
+			got, err := kindRegistryArgs(args, dir)
+			if err != nil {
+				t.Fatalf("kindRegistryArgs() error = %v", err)
+			}
+			path := filepath.Join(dir, "kind-config.yaml")
+			if want := []string{"--config", path, "--retain"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("kindRegistryArgs() = %v, want %v", got, want)
+			}
+
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var config struct {
+				Kind                    string        `yaml:"kind"`
+				APIVersion              string        `yaml:"apiVersion"`
+				ContainerdConfigPatches []string      `yaml:"containerdConfigPatches"`
+				Nodes                   []interface{} `yaml:"nodes"`
+			}
+			if err := yaml.Unmarshal(b, &config); err != nil {
+				t.Fatalf("patched config isn't valid: %v\n%s", err, b)
+			}
+			if config.Kind != "Cluster" || config.APIVersion != "kind.x-k8s.io/v1alpha4" {
+				t.Errorf("patched config has kind %q and apiVersion %q, want Cluster and kind.x-k8s.io/v1alpha4", config.Kind, config.APIVersion)
+			}
+			if !reflect.DeepEqual(config.ContainerdConfigPatches, tc.wantPatches) {
+				t.Errorf("patched config has containerdConfigPatches %q, want %q", config.ContainerdConfigPatches, tc.wantPatches)
+			}
+			if len(config.Nodes) != tc.wantNodes {
+				t.Errorf("patched config has %d nodes, want %d", len(config.Nodes), tc.wantNodes)
+			}
+		})
+	}
+}
@@ -7,75 +7,100 @@ import (
 )
 
 // StartMinikubeRegistryDocker starts minikube for docker registry.
-func StartMinikubeRegistryDocker(profile string, args ...string) error {
-	return startMinikubeRegistry(profile, "docker", args...)
+func StartMinikubeRegistryDocker(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startMinikubeRegistry(opts, "docker", args...)
 }
 
 // StartMinikubeRegistryContainerd starts minikube for containerd registry.
-func StartMinikubeRegistryContainerd(profile string, args ...string) error {
-	return startMinikubeRegistry(profile, "containerd", args...)
+func StartMinikubeRegistryContainerd(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startMinikubeRegistry(opts, "containerd", args...)
 }
 
 // StartMinikubeRegistryCrio start minikube for crio registry.
-func StartMinikubeRegistryCrio(profile string, args ...string) error {
-	return startMinikubeRegistry(profile, "cri-o", args...)
+func StartMinikubeRegistryCrio(opts RunOptions, args ...string) (ClusterStart, error) {
+	return startMinikubeRegistry(opts, "cri-o", args...)
 }
 
-func startMinikubeRegistry(profile string, runtime string, otherStartArgs ...string) error {
+func startMinikubeRegistry(opts RunOptions, runtime string, otherStartArgs ...string) (ClusterStart, error) {
 	runtime = fmt.Sprintf("--container-runtime=%s", runtime)
 	arguments := append([]string{runtime}, otherStartArgs...)
-	if err := startMinikube(profile, arguments...); err != nil {
-		return err
-	}
-
-	if err := setDockerInsecureRegistry(profile); err != nil {
-		return err
+	s, err := startMinikube(opts, arguments...)
+	if err != nil {
+		return s, err
 	}
 
-	// setDockerInsecureRegistry restarts docker, so minikube needs to be restarted
-	if err := startMinikube(profile, arguments...); err != nil {
-		return err
+	if err := enableRegistryAddon(opts.Profile); err != nil {
+		return s, err
 	}
 
-	return enableRegistryAddon(profile)
-
+	return s, startRegistryProxy(opts.Profile)
 }
 
 // RunRegistry builds and pushes the provided image using the registry addon method and returns the run time.
-func RunRegistry(image string, profile string) (float64, error) {
+func RunRegistry(image string, opts RunOptions) (Timing, error) {
 	// build
-	dockerfile := fmt.Sprintf("testdata/Dockerfile.%s", image)
-	tag := fmt.Sprintf("$(./minikube -p %s ip):5000/benchmark-registry", profile)
-	buildArgs := fmt.Sprintf("docker build -t %s -f %s .", tag, dockerfile)
-	build := exec.Command("/bin/bash", "-c", buildArgs)
+	tag := registryProxy + "/benchmark-registry"
+	build := dockerBuild(opts.Builder, tag, image)
 	start := time.Now()
 	if _, err := run(build); err != nil {
-		return 0, fmt.Errorf("failed to build via registry: %v", err)
+		return Timing{}, fmt.Errorf("failed to build via registry: %v", err)
 	}
 
 	// push
 	pushArgs := fmt.Sprintf("docker push %s", tag)
 	push := exec.Command("/bin/bash", "-c", pushArgs)
+	transferStart := time.Now()
 	if _, err := run(push); err != nil {
-		return 0, fmt.Errorf("failed to push via registry: %v", err)
+		return Timing{}, fmt.Errorf("failed to push via registry: %v", err)
+	}
+	// the registry addon's proxy exposes the registry on port 5000 of every node, which each node pulls from
+	if opts.Nodes > 1 {
+		if err := pullOnEveryNode(opts.Profile, "localhost:5000/benchmark-registry"); err != nil {
+			return Timing{}, err
+		}
 	}
 	elapsed := time.Now().Sub(start)
+	transfer := time.Now().Sub(transferStart)
 
 	// verify
-	ip, err := minikubeIP(profile)
-	if err != nil {
-		return 0, err
+	if opts.Nodes > 1 {
+		if err := verifyEveryNode(opts.Profile, "benchmark-registry"); err != nil {
+			return Timing{}, fmt.Errorf("image was not found after registry: %v", err)
+		}
 	}
-
-	verifyArgs := fmt.Sprintf("curl http://%s:5000/v2/_catalog | grep benchmark-registry", ip)
+	verifyArgs := fmt.Sprintf("curl http://%s/v2/_catalog | grep benchmark-registry", registryProxy)
 	verify := exec.Command("/bin/bash", "-c", verifyArgs)
 	o, err := run(verify)
 	if err != nil {
-		return 0, fmt.Errorf("failed to check if image was pushed successfully: %v", err)
+		return Timing{}, fmt.Errorf("failed to check if image was pushed successfully: %v", err)
 	}
 	if string(o) == "" {
-		return 0, fmt.Errorf("image was not successfully pushed")
+		return Timing{}, fmt.Errorf("image was not successfully pushed")
 	}
 
-	return elapsed.Seconds(), nil
+	return Timing{Total: elapsed.Seconds(), Transfer: transfer.Seconds()}, nil
+}
+
+// resetRegistryAddon empties the registry addon, clearing out any other caching with the provided cache clear beforehand.
+// The addon of a reused cluster may hold images that weren't pushed by the benchmark, so it's never emptied,
+// the registry methods aren't run on a reused cluster.
+func resetRegistryAddon(opts RunOptions, cacheClear func(opts RunOptions) error) error {
+	if opts.ReuseCluster {
+		return fmt.Errorf("refusing to empty the registry addon of a reused cluster, it may hold images that weren't pushed by the benchmark")
+	}
+	if err := cacheClear(opts); err != nil {
+		return err
+	}
+
+	// the addon stores the images in its registry container, so they're removed along with it
+	c := exec.Command("./minikube", "-p", opts.Profile, "addons", "disable", "registry")
+	if _, err := run(c); err != nil {
+		return fmt.Errorf("failed to disable registry addon: %v", err)
+	}
+	return enableRegistryAddon(opts.Profile)
+}
+
+// ClearRegistryAddonAndDockerAndMinikubeDockerCache clears out the registry addon along with Dockers caching on the host and in minikube.
+func ClearRegistryAddonAndDockerAndMinikubeDockerCache(opts RunOptions) error {
+	return resetRegistryAddon(opts, ClearDockerAndMinikubeDockerCache)
 }
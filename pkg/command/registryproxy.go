@@ -0,0 +1,44 @@
+package command
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+const (
+	// registryProxyName is the name of the container forwarding the host's localhost:5000 to the registry addon.
+	registryProxyName = "benchmark-registry-proxy"
+	// registryProxy is the address the registry addon is reachable at from the host via the proxy.
+	// localhost registries are treated as insecure by Docker, so Docker's config doesn't have to be modified.
+	registryProxy = "localhost:5000"
+)
+
+// startRegistryProxy runs a container on the host network that forwards localhost:5000 to the registry addon.
+func startRegistryProxy(profile string) error {
+	if err := deleteRegistryProxy(); err != nil {
+		return err
+	}
+
+	ip, err := minikubeIP(profile)
+	if err != nil {
+		return err
+	}
+
+	c := exec.Command("docker", "run", "-d", "--network=host", "--name", registryProxyName, "alpine/socat", "TCP-LISTEN:5000,reuseaddr,fork", fmt.Sprintf("TCP:%s:5000", ip))
+	if _, err := run(c); err != nil {
+		return fmt.Errorf("failed to start registry proxy: %v", err)
+	}
+
+	return nil
+}
+
+// deleteRegistryProxy removes the registry proxy container, if it exists.
+func deleteRegistryProxy() error {
+	args := fmt.Sprintf("if docker container inspect %[1]s > /dev/null 2>&1; then docker rm -f %[1]s; fi", registryProxyName)
+	c := exec.Command("/bin/bash", "-c", args)
+	if _, err := run(c); err != nil {
+		return fmt.Errorf("failed to delete registry proxy: %v", err)
+	}
+
+	return nil
+}
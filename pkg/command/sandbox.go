@@ -0,0 +1,171 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// sandboxName is the name of the container running the isolated Docker daemon.
+	sandboxName = "benchmark-dockerd"
+	// sandboxVolume is the volume used as the data-root of the isolated Docker daemon.
+	sandboxVolume = "benchmark-dockerd"
+	// sandboxSubnet is the subnet the isolated Docker daemon allocates networks from, so they don't overlap with the host daemon's.
+	sandboxSubnet = "10.201.0.0/16"
+	// sandboxAddressPool is the address pool of the isolated Docker daemon, which splits sandboxSubnet into /24 networks.
+	sandboxAddressPool = "base=" + sandboxSubnet + ",size=24"
+	// sandboxNetwork is the network the containers run by the benchmark in the isolated Docker daemon are attached to,
+	// as the daemon has no default bridge network.
+	sandboxNetwork = "benchmark-sandbox"
+)
+
+// sandboxRule is an iptables rule of the provided chain in the provided table.
+type sandboxRule struct {
+	table string
+	chain string
+	spec  []string
+}
+
+// sandboxRules are the iptables rules letting the networks of the isolated Docker daemon reach each other and the outside,
+// as the daemon doesn't manage iptables so it leaves the host daemon's chains alone.
+var sandboxRules = []sandboxRule{
+	{"nat", "POSTROUTING", []string{"-s", sandboxSubnet, "!", "-d", sandboxSubnet, "-j", "MASQUERADE"}},
+	{"filter", "FORWARD", []string{"-s", sandboxSubnet, "-j", "ACCEPT"}},
+	{"filter", "FORWARD", []string{"-d", sandboxSubnet, "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "ACCEPT"}},
+}
+
+// sandbox contains the state of the isolated Docker daemon, if one was started.
+var sandbox = struct {
+	// socketDir is the host directory containing the socket of the isolated Docker daemon.
+	socketDir string
+	// dockerHost is the value of DOCKER_HOST before the isolated Docker daemon was started.
+	dockerHost *string
+}{}
+
+// StartDockerSandbox starts a Docker daemon with its own data-root and socket in a docker:dind container and points
+// DOCKER_HOST at it, so every Docker command run afterwards, including the ones run by minikube, kind and k3d,
+// uses it instead of the host daemon.
+// The container shares the host network, so addresses such as localhost:5001 and the ports minikube, kind and k3d publish
+// are reachable as they are without it. The daemon doesn't manage iptables, which would reset the host daemon's chains,
+// its published ports are forwarded by docker-proxy instead and the few rules its networks need are added separately.
+func StartDockerSandbox() error {
+	dir, err := os.MkdirTemp("", "benchmark-dockerd")
+	if err != nil {
+		return fmt.Errorf("failed to create isolated Docker daemon socket dir: %v", err)
+	}
+	sandbox.socketDir = dir
+
+	// give the host docker group access to the socket, so the benchmark doesn't have to run as root
+	group := "0"
+	if g, err := user.LookupGroup("docker"); err == nil {
+		group = g.Gid
+	}
+	c := exec.Command("docker", "run", "-d", "--privileged", "--network=host", "--name", sandboxName,
+		"-v", sandboxVolume+":/var/lib/docker", "-v", dir+":/run/benchmark",
+		"docker:dind", "dockerd", "--host=unix:///run/benchmark/docker.sock", "--group="+group,
+		"--bridge=none", "--iptables=false", "--ip6tables=false", "--default-address-pool="+sandboxAddressPool)
+	if _, err := run(c); err != nil {
+		return fmt.Errorf("failed to start isolated Docker daemon: %v", err)
+	}
+
+	host := "unix://" + filepath.Join(dir, "docker.sock")
+	if err := waitForDocker(host); err != nil {
+		return err
+	}
+
+	// the rules are added from the daemon's container, as it shares the host network and is privileged
+	for _, rule := range sandboxRules {
+		a := append([]string{"exec", sandboxName, "iptables", "-t", rule.table, "-I", rule.chain, "1"}, rule.spec...)
+		if _, err := run(exec.Command("docker", a...)); err != nil {
+			return fmt.Errorf("failed to add isolated Docker daemon network rule: %v", err)
+		}
+	}
+
+	c = exec.Command("docker", "-H", host, "network", "create", sandboxNetwork)
+	if _, err := run(c); err != nil {
+		return fmt.Errorf("failed to create isolated Docker daemon network: %v", err)
+	}
+
+	if h, ok := os.LookupEnv("DOCKER_HOST"); ok {
+		sandbox.dockerHost = &h
+	}
+	if err := os.Setenv("DOCKER_HOST", host); err != nil {
+		return fmt.Errorf("failed to set DOCKER_HOST: %v", err)
+	}
+
+	return nil
+}
+
+// sandboxRunArgs returns the docker run args attaching a container to the network of the isolated Docker daemon, if it's running.
+func sandboxRunArgs() []string {
+	if sandbox.socketDir == "" {
+		return nil
+	}
+	return []string{"--network", sandboxNetwork}
+}
+
+// waitForDocker waits for the Docker daemon at the provided host to respond.
+func waitForDocker(host string) error {
+	var err error
+	for i := 0; i < 30; i++ {
+		c := exec.Command("docker", "-H", host, "info")
+		if _, err = run(c); err == nil {
+			return nil
+		}
+		time.Sleep(time.Second)
+	}
+	return fmt.Errorf("isolated Docker daemon didn't become ready: %v", err)
+}
+
+// StopDockerSandbox points DOCKER_HOST back at the host daemon and removes the isolated Docker daemon along with its data-root,
+// which removes everything that was created in it as well.
+func StopDockerSandbox() error {
+	if sandbox.socketDir == "" {
+		return nil
+	}
+
+	if sandbox.dockerHost != nil {
+		os.Setenv("DOCKER_HOST", *sandbox.dockerHost)
+	} else {
+		os.Unsetenv("DOCKER_HOST")
+	}
+
+	if err := DeleteDockerSandbox(); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(sandbox.socketDir); err != nil {
+		return fmt.Errorf("failed to remove isolated Docker daemon socket dir: %v", err)
+	}
+	sandbox.socketDir = ""
+
+	return nil
+}
+
+// DeleteDockerSandbox removes the network rules of the isolated Docker daemon, then its container along with its data-root, if they exist.
+func DeleteDockerSandbox() error {
+	deleteRules := []string{}
+	for _, rule := range sandboxRules {
+		a := append([]string{"iptables", "-t", rule.table, "-D", rule.chain}, rule.spec...)
+		deleteRules = append(deleteRules, "while "+shellQuote(a)+" 2> /dev/null; do :; done")
+	}
+	args := fmt.Sprintf("if docker container inspect %[1]s > /dev/null 2>&1; then docker exec %[1]s sh -c %[2]s; docker rm -f %[1]s; fi",
+		sandboxName, shellQuote([]string{strings.Join(deleteRules, "; ")}))
+	c := exec.Command("/bin/bash", "-c", args)
+	if _, err := run(c); err != nil {
+		return fmt.Errorf("failed to remove isolated Docker daemon: %v", err)
+	}
+
+	args = fmt.Sprintf("if docker volume inspect %[1]s > /dev/null 2>&1; then docker volume rm %[1]s; fi", sandboxVolume)
+	c = exec.Command("/bin/bash", "-c", args)
+	if _, err := run(c); err != nil {
+		return fmt.Errorf("failed to remove isolated Docker daemon data-root: %v", err)
+	}
+
+	return nil
+}
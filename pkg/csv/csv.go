@@ -4,18 +4,22 @@ package csv
 import (
 	"encoding/csv"
 	"fmt"
-	"log"
 	"os"
 
 	"benchmark/pkg/benchmark"
 )
 
-// WriteTo writes the benchmarking results out to a csv.
-func WriteTo(ag benchmark.AggregatedResultsMatrix) error {
-	records := [][]string{{"image"}}
-	for _, method := range benchmark.BenchMethods {
+// WriteTo writes the benchmarking results out to a csv, along with the metadata of every method to another csv.
+func WriteTo(results *benchmark.Results) error {
+	if err := writeMethods(results); err != nil {
+		return err
+	}
+
+	records := [][]string{{"image", "size (MB)", "layers", "image id"}}
+	for _, method := range results.Methods {
 		for _, iter := range benchmark.Iter {
-			records[0] = append(records[0], method.Name+iter+" average", method.Name+iter+" standard deviation")
+			records[0] = append(records[0], method+iter+" average", method+iter+" standard deviation", method+iter+" throughput (MB/s)", method+iter+" save average",
+				method+iter+" cache clear average", method+iter+" wall clock", method+iter+" measured", method+iter+" overhead")
 		}
 	}
 
@@ -26,11 +30,15 @@ func WriteTo(ag benchmark.AggregatedResultsMatrix) error {
 	w := csv.NewWriter(f)
 
 	for _, image := range benchmark.Images {
-		imageRecords := []string{image}
-		for _, method := range benchmark.BenchMethods {
+		imageRecords := []string{image, "", "", ""}
+		if info, ok := results.Images[image]; ok {
+			imageRecords = []string{image, fmt.Sprintf("%.2f", float64(info.Size)/1e6), fmt.Sprintf("%d", info.Layers), info.ID}
+		}
+		for _, method := range results.Methods {
 			for _, iter := range benchmark.Iter {
-				run := ag[image][method.Name+iter]
-				imageRecords = append(imageRecords, fmt.Sprintf("%.2f", run.Avg), fmt.Sprintf("%.2f", run.Std))
+				run := results.Matrix[image][method+iter]
+				imageRecords = append(imageRecords, fmt.Sprintf("%.2f", run.Avg), fmt.Sprintf("%.2f", run.Std), fmt.Sprintf("%.2f", run.Throughput), fmt.Sprintf("%.2f", run.SaveAvg),
+					fmt.Sprintf("%.2f", run.CacheClearAvg), fmt.Sprintf("%.2f", run.Wall), fmt.Sprintf("%.2f", run.Measured), fmt.Sprintf("%.2f", run.Overhead))
 			}
 		}
 		records = append(records, imageRecords)
@@ -38,12 +46,33 @@ func WriteTo(ag benchmark.AggregatedResultsMatrix) error {
 
 	for _, record := range records {
 		if err := w.Write(record); err != nil {
-			log.Fatalln("error writing record to csv:", err)
+			return fmt.Errorf("error writing record to csv: %v", err)
 		}
 	}
 	w.Flush()
-	if err := w.Error(); err != nil {
-		log.Fatal(err)
+	return w.Error()
+}
+
+// writeMethods writes the metadata of every benchmarked method out to a csv, methods that weren't run have no metadata.
+func writeMethods(results *benchmark.Results) error {
+	records := [][]string{{"method", "start command", "cluster create (s)", "cluster ready (s)", "method setup (s)", "cluster delete (s)"}}
+	for _, method := range results.Methods {
+		cmd, ok := results.StartCommands[method]
+		if !ok {
+			continue
+		}
+		t := results.ClusterTimes[method]
+		records = append(records, []string{method, cmd, fmt.Sprintf("%.2f", t.Create), fmt.Sprintf("%.2f", t.Ready), fmt.Sprintf("%.2f", t.Setup), fmt.Sprintf("%.2f", t.Delete)})
+	}
+
+	f, err := os.Create("out/methods.csv")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	if err := w.WriteAll(records); err != nil {
+		return fmt.Errorf("failed to write methods csv: %v", err)
 	}
 	return nil
 }
@@ -0,0 +1,116 @@
+// Package safety snapshots the host state the benchmark touches before it's run, so that state unrelated to the
+// benchmark isn't silently destroyed and the config the benchmark changes can be restored afterwards.
+package safety
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"benchmark/pkg/command"
+)
+
+// Snapshot contains the host state the benchmark touches.
+type Snapshot struct {
+	// KubeContext is the current context of the kubeconfig, which creating a cluster switches, it's empty if none was set.
+	KubeContext      string
+	MinikubeProfiles []string
+	KindClusters     []string
+	K3dClusters      []string
+	DockerImages     []string
+
+	// profile is the minikube profile used by the benchmark.
+	profile string
+}
+
+// Take snapshots the current host state, profile is the minikube profile used by the benchmark.
+func Take(profile string) (*Snapshot, error) {
+	s := &Snapshot{profile: profile}
+
+	var err error
+	if s.KubeContext, err = kubeContext(); err != nil {
+		return nil, err
+	}
+	if s.MinikubeProfiles, err = command.ListMinikubeProfiles(); err != nil {
+		return nil, err
+	}
+	if s.KindClusters, err = command.ListKindClusters(); err != nil {
+		return nil, err
+	}
+	if s.K3dClusters, err = command.ListK3dClusters(); err != nil {
+		return nil, err
+	}
+	if s.DockerImages, err = command.ListDockerImages(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Save writes the snapshot out to the provided file, so the state prior to the benchmark can be reviewed.
+func (s *Snapshot) Save(path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %v", err)
+	}
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		return fmt.Errorf("failed to write snapshot: %v", err)
+	}
+	return nil
+}
+
+// Unrelated returns a description of every piece of state in the snapshot that wasn't created by the benchmark.
+// Docker images aren't included, as only the images labeled by the benchmark, or pulled by it when they didn't exist
+// before the run, are ever removed, so the images in the snapshot can't be deleted by it.
+func (s *Snapshot) Unrelated() []string {
+	res := []string{}
+	for _, p := range s.MinikubeProfiles {
+		if p != s.profile {
+			res = append(res, "minikube profile "+p)
+		}
+	}
+	for _, c := range s.KindClusters {
+		if c != s.profile {
+			res = append(res, "kind cluster "+c)
+		}
+	}
+	for _, c := range s.K3dClusters {
+		if c != s.profile {
+			res = append(res, "k3d cluster "+c)
+		}
+	}
+	return res
+}
+
+// Restore switches the kubeconfig back to the current context in the snapshot, if it was changed since it was taken.
+func (s *Snapshot) Restore() error {
+	current, err := kubeContext()
+	if err != nil {
+		return err
+	}
+	if current == s.KubeContext {
+		return nil
+	}
+
+	log.Printf("restoring kubeconfig current-context %q", s.KubeContext)
+	c := exec.Command("kubectl", "config", "unset", "current-context")
+	if s.KubeContext != "" {
+		c = exec.Command("kubectl", "config", "use-context", s.KubeContext)
+	}
+	if o, err := c.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to restore kubeconfig current-context: %v\noutput: %s", err, string(o))
+	}
+	return nil
+}
+
+// kubeContext returns the current context of the kubeconfig, or an empty string if none is set.
+func kubeContext() (string, error) {
+	o, err := exec.Command("kubectl", "config", "view", "-o", "jsonpath={.current-context}").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get kubeconfig current-context: %v", err)
+	}
+	return strings.TrimSpace(string(o)), nil
+}